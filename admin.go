@@ -0,0 +1,704 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"epgo/internal/atomicfile"
+)
+
+// Web admin UI on /admin/ (Server: Admin UI). It is the browser counterpart of
+// the Configure menu and uses the same Config and SD methods.
+
+type adminCountry struct {
+	Region    string
+	ShortName string
+	FullName  string
+	Example   string
+}
+
+type adminPage struct {
+	Title   string
+	Message string
+	Error   string
+
+	// Dashboard
+	ConfigFile string
+	XMLTV      string
+	XMLTVTime  time.Time
+	Expires    time.Time
+	MaxLineups int64
+	Lineups    []cliLineup
+	Stations   []channel
+	Refreshing bool
+
+	// Lineup search
+	Countries  []adminCountry
+	Country    string
+	PostalCode string
+	Found      []Entry
+
+	// Channels
+	Lineup   string
+	Channels []cliChannel
+
+	// Config and overrides editors
+	Text string
+
+	// Image browser
+	Query    string
+	Station  string
+	Programs []programImages
+}
+
+// adminHandler returns the admin UI behind HTTP basic auth.
+func adminHandler() http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/admin/", adminIndex)
+	mux.HandleFunc("/admin/lineups/search", adminLineupSearch)
+	mux.HandleFunc("/admin/lineups/add", adminLineupChange("add"))
+	mux.HandleFunc("/admin/lineups/remove", adminLineupChange("remove"))
+	mux.HandleFunc("/admin/channels", adminChannels)
+	mux.HandleFunc("/admin/config", adminConfig)
+	mux.HandleFunc("/admin/overrides", adminOverrides)
+	mux.HandleFunc("/admin/images", adminImages)
+	mux.HandleFunc("/admin/images/pick", adminImagePick)
+	mux.HandleFunc("/admin/images/thumb/", adminImageThumb)
+	mux.HandleFunc("/admin/refresh", adminRefresh)
+
+	return adminAuth(mux)
+}
+
+// adminAuth checks the credentials from Server: Admin UI on every request, so
+// a password changed in the UI applies right away. Form posts from other
+// origins are refused because browsers resend basic auth credentials.
+func adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		admin := currentConfig().Server.Admin
+		username, password := admin.Username, admin.Password
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || len(password) == 0 ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="EPGo admin", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+			logger.Warn("Admin: cross-origin request refused", "path", r.URL.Path, "origin", r.Header.Get("Origin"), "referer", r.Referer())
+			http.Error(w, "cross-origin request refused", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Frame-Options", "DENY")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether the Origin (or Referer) header, if sent, names the requested host.
+func sameOrigin(r *http.Request) bool {
+
+	source := r.Header.Get("Origin")
+	if len(source) == 0 {
+		source = r.Referer()
+	}
+	if len(source) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// adminSD logs in with the cached token and loads the account status.
+func adminSD() (sd *SD, err error) {
+
+	sd = &SD{}
+
+	if err = sd.Init(); err != nil {
+		return
+	}
+
+	if err = applyCachedToken(sd); err != nil {
+		return
+	}
+
+	err = sd.Status()
+	return
+}
+
+// adminLock keeps config changes from the UI from overlapping a refresh,
+// which re-reads the config file. The caller unlocks refreshMu.
+func adminLock(w http.ResponseWriter, r *http.Request, target string) bool {
+	if !refreshMu.TryLock() {
+		adminRedirect(w, r, target, "", "A refresh is running; try again when it has finished.")
+		return false
+	}
+	return true
+}
+
+// adminRedirect sends the browser back to a page (Post/Redirect/Get) with a message.
+func adminRedirect(w http.ResponseWriter, r *http.Request, target, message, errMessage string) {
+
+	q := url.Values{}
+	if len(message) != 0 {
+		q.Set("msg", message)
+	}
+	if len(errMessage) != 0 {
+		q.Set("error", errMessage)
+	}
+
+	if len(q) != 0 {
+		if strings.Contains(target, "?") {
+			target += "&" + q.Encode()
+		} else {
+			target += "?" + q.Encode()
+		}
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func adminRender(w http.ResponseWriter, r *http.Request, name string, page adminPage) {
+
+	if len(page.Message) == 0 {
+		page.Message = r.URL.Query().Get("msg")
+	}
+	if len(page.Error) == 0 {
+		page.Error = r.URL.Query().Get("error")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := adminTemplates.ExecuteTemplate(w, name, page); err != nil {
+		logger.Error("Admin: unable to render page", "page", name, "error", err)
+	}
+}
+
+func adminIndex(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path != "/admin/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var cfg = currentConfig()
+	var page = adminPage{
+		Title:      "Overview",
+		ConfigFile: configFileName(),
+		XMLTV:      cfg.Files.XMLTV,
+		Stations:   cfg.Station,
+		Refreshing: refreshRunning(),
+	}
+
+	if fi, err := os.Stat(cfg.Files.XMLTV); err == nil {
+		page.XMLTVTime = fi.ModTime()
+	}
+
+	sd, err := adminSD()
+	if err != nil {
+		page.Error = fmt.Sprintf("Schedules Direct: %v", err)
+	} else {
+		page.Expires = sd.Resp.Status.Account.Expires
+		page.MaxLineups = sd.Resp.Status.Account.MaxLineups
+		for _, l := range sd.Resp.Status.Lineups {
+			page.Lineups = append(page.Lineups, cliLineup{Lineup: l.Lineup, Name: l.Name, Modified: l.Modified})
+		}
+	}
+
+	adminRender(w, r, "index", page)
+}
+
+func adminLineupSearch(w http.ResponseWriter, r *http.Request) {
+
+	var page = adminPage{
+		Title:      "Add Lineup",
+		Country:    strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("country"))),
+		PostalCode: strings.TrimSpace(r.URL.Query().Get("postalcode")),
+	}
+
+	sd, err := adminSD()
+	if err != nil {
+		page.Error = fmt.Sprintf("Schedules Direct: %v", err)
+		adminRender(w, r, "search", page)
+		return
+	}
+
+	if err = sd.Countries(); err != nil {
+		page.Error = fmt.Sprintf("Schedules Direct: %v", err)
+		adminRender(w, r, "search", page)
+		return
+	}
+
+	for _, region := range countryRegions(sd.Resp.Countries) {
+		for _, c := range sd.Resp.Countries[region] {
+			page.Countries = append(page.Countries, adminCountry{
+				Region:    region,
+				ShortName: c.ShortName,
+				FullName:  c.FullName,
+				Example:   c.PostalCodeExample,
+			})
+		}
+	}
+
+	if len(page.Country) != 0 && len(page.PostalCode) != 0 {
+		sd.Req.Parameter = fmt.Sprintf("?country=%s&postalcode=%s", url.QueryEscape(page.Country), url.QueryEscape(page.PostalCode))
+		if err = sd.Headends(); err != nil {
+			page.Error = fmt.Sprintf("Schedules Direct: %v", err)
+		} else {
+			page.Found = headendLineups(sd)
+			if len(page.Found) == 0 {
+				page.Message = "No lineups found for this postal code."
+			}
+		}
+	}
+
+	adminRender(w, r, "search", page)
+}
+
+func adminLineupChange(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		lineup := strings.TrimSpace(r.FormValue("lineup"))
+		if len(lineup) == 0 {
+			adminRedirect(w, r, "/admin/", "", "No lineup selected.")
+			return
+		}
+		if !isLineupID(lineup) {
+			adminRedirect(w, r, "/admin/", "", "Invalid lineup ID.")
+			return
+		}
+
+		sd, err := adminSD()
+		if err != nil {
+			adminRedirect(w, r, "/admin/", "", fmt.Sprintf("Schedules Direct: %v", err))
+			return
+		}
+
+		sd.Req.Parameter = fmt.Sprintf("/%s", lineup)
+		sd.Req.Type = "PUT"
+		if action == "remove" {
+			sd.Req.Type = "DELETE"
+		}
+
+		err = sd.Lineups()
+		if err != nil {
+			if len(sd.Resp.Lineup.Message) != 0 {
+				err = fmt.Errorf("%s: %w", sd.Resp.Lineup.Message, err)
+			}
+			adminRedirect(w, r, "/admin/", "", fmt.Sprintf("Lineup %s: %v", lineup, err))
+			return
+		}
+
+		logger.Info("Admin: lineup changed", "action", action, "lineup", lineup, "changes_remaining", sd.Resp.Lineup.ChangesRemaining)
+		adminRedirect(w, r, "/admin/", fmt.Sprintf("Lineup %s: %s (changes remaining: %d)", lineup, action, sd.Resp.Lineup.ChangesRemaining), "")
+	}
+}
+
+func adminChannels(w http.ResponseWriter, r *http.Request) {
+
+	lineup := strings.TrimSpace(r.FormValue("lineup"))
+	if len(lineup) == 0 {
+		adminRedirect(w, r, "/admin/", "", "No lineup selected.")
+		return
+	}
+	if !isLineupID(lineup) {
+		adminRedirect(w, r, "/admin/", "", "Invalid lineup ID.")
+		return
+	}
+
+	target := "/admin/channels?lineup=" + url.QueryEscape(lineup)
+
+	sd, err := adminSD()
+	if err != nil {
+		adminRedirect(w, r, "/admin/", "", fmt.Sprintf("Schedules Direct: %v", err))
+		return
+	}
+
+	channels, err := lineupChannels(sd, lineup)
+	if err != nil {
+		adminRedirect(w, r, "/admin/", "", fmt.Sprintf("Lineup %s: %v", lineup, err))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		adminRender(w, r, "channels", adminPage{Title: "Channels " + lineup, Lineup: lineup, Channels: channels})
+		return
+	}
+
+	if !adminLock(w, r, target) {
+		return
+	}
+	defer refreshMu.Unlock()
+
+	var selected = make(map[string]bool)
+	for _, id := range r.PostForm["station"] {
+		selected[id] = true
+	}
+
+	// Changes go to a copy, which replaces Config once it is saved
+	var next = Config
+
+	var lineupOf = make(map[string]string)
+	for _, st := range next.Station {
+		lineupOf[st.ID] = st.Lineup
+	}
+
+	var added, removed int
+	for _, ch := range channels {
+		switch {
+		case selected[ch.StationID] && !ch.Configured:
+			next.AddChannel(&channel{Name: ch.Name, ID: ch.StationID, Lineup: ch.Lineup})
+			added++
+		case !selected[ch.StationID] && ch.Configured && lineupOf[ch.StationID] == lineup:
+			next.RemoveChannel(&channel{ID: ch.StationID})
+			removed++
+		}
+	}
+
+	if err = next.Save(); err != nil {
+		adminRedirect(w, r, target, "", fmt.Sprintf("Unable to save the config: %v", err))
+		return
+	}
+
+	configMu.Lock()
+	Config = next
+	configMu.Unlock()
+
+	logger.Info("Admin: channels changed", "lineup", lineup, "added", added, "removed", removed)
+	adminRedirect(w, r, target, fmt.Sprintf("%d channels added, %d removed.", added, removed), "")
+}
+
+func adminConfig(w http.ResponseWriter, r *http.Request) {
+
+	var page = adminPage{Title: "Configuration", ConfigFile: configFileName()}
+
+	if r.Method != http.MethodPost {
+		data, err := os.ReadFile(page.ConfigFile)
+		if err != nil {
+			page.Error = err.Error()
+		}
+		page.Text = string(data)
+		adminRender(w, r, "config", page)
+		return
+	}
+
+	page.Text = r.FormValue("config")
+
+	var check config
+	if err := yaml.Unmarshal([]byte(page.Text), &check); err != nil {
+		page.Error = fmt.Sprintf("Invalid YAML: %v", err)
+		adminRender(w, r, "config", page)
+		return
+	}
+
+	if len(strings.TrimSpace(check.Options.RefreshSchedule)) != 0 {
+		if _, err := parseRefreshSchedule(check.Options.RefreshSchedule); err != nil {
+			page.Error = err.Error()
+			adminRender(w, r, "config", page)
+			return
+		}
+	}
+
+	if err := check.compileChannelFilter(); err != nil {
+		page.Error = err.Error()
+		adminRender(w, r, "config", page)
+		return
+	}
+
+	if err := check.loadOutputTimezone(); err != nil {
+		page.Error = err.Error()
+		adminRender(w, r, "config", page)
+		return
+	}
+
+	if !adminLock(w, r, "/admin/config") {
+		return
+	}
+	defer refreshMu.Unlock()
+
+	if err := atomicfile.WriteFile(page.ConfigFile, []byte(page.Text), 0644); err != nil {
+		page.Error = fmt.Sprintf("Unable to save the config: %v", err)
+		adminRender(w, r, "config", page)
+		return
+	}
+
+	if err := reloadConfig(); err != nil {
+		adminRedirect(w, r, "/admin/config", "", fmt.Sprintf("Unable to load the config: %v", err))
+		return
+	}
+
+	logger.Info("Admin: config saved", "filename", page.ConfigFile)
+	adminRedirect(w, r, "/admin/config", "Configuration saved. It is used from the next refresh on.", "")
+}
+
+func adminOverrides(w http.ResponseWriter, r *http.Request) {
+
+	var page = adminPage{Title: "Poster Overrides", ConfigFile: overridesFilePath()}
+
+	if r.Method != http.MethodPost {
+		data, err := os.ReadFile(page.ConfigFile)
+		if err != nil && !os.IsNotExist(err) {
+			page.Error = err.Error()
+		}
+		page.Text = string(data)
+		adminRender(w, r, "overrides", page)
+		return
+	}
+
+	page.Text = strings.ReplaceAll(r.FormValue("overrides"), "\r\n", "\n")
+
+	records, problems := parseOverrides([]byte(page.Text))
+	if len(problems) != 0 {
+		var lines []string
+		for _, p := range problems {
+			lines = append(lines, fmt.Sprintf("line %d: %v", p.Line, p.Err))
+		}
+		page.Error = "Not saved: " + strings.Join(lines, "; ")
+		adminRender(w, r, "overrides", page)
+		return
+	}
+
+	if len(page.Text) != 0 && !strings.HasSuffix(page.Text, "\n") {
+		page.Text += "\n"
+	}
+
+	// setOverride (image browser) and the API write the file as well
+	overridesFileMu.Lock()
+	err := atomicfile.WriteFile(page.ConfigFile, []byte(page.Text), 0644)
+	overridesFileMu.Unlock()
+	if err != nil {
+		page.Error = fmt.Sprintf("Unable to save the overrides: %v", err)
+		adminRender(w, r, "overrides", page)
+		return
+	}
+
+	overridesReload()
+
+	logger.Info("Admin: poster overrides saved", "path", page.ConfigFile, "count", len(records))
+	adminRedirect(w, r, "/admin/overrides", fmt.Sprintf("%d overrides saved.", len(records)), "")
+}
+
+func adminRefresh(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !startRefresh(configFileName()) {
+		adminRedirect(w, r, "/admin/", "", "A refresh is already running.")
+		return
+	}
+
+	logger.Info("Admin: EPG refresh started")
+	adminRedirect(w, r, "/admin/", "EPG refresh started.", "")
+}
+
+// sortedStations returns the configured stations ordered by lineup and name.
+func sortedStations(stations []channel) []channel {
+	sorted := append([]channel(nil), stations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Lineup != sorted[j].Lineup {
+			return sorted[i].Lineup < sorted[j].Lineup
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+var adminTemplates = template.Must(template.New("admin").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	"join":   strings.Join,
+	"sorted": sortedStations,
+}).Parse(adminHTML))
+
+const adminHTML = `
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - EPGo</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 60em; padding: 0 1em 2em; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: .3em .5em; text-align: left; }
+textarea { font-family: monospace; width: 100%; }
+form.inline { display: inline; }
+.images { display: flex; flex-wrap: wrap; gap: 1em; }
+.images figure { border: 1px solid #ddd; margin: 0; padding: .5em; width: 12em; }
+.images figure.override { border: 2px solid #1a73e8; }
+.images img { display: block; max-height: 12em; max-width: 100%; }
+.images figcaption { font-size: .85em; }
+.msg { background: #e6f4ea; padding: .5em; }
+.err { background: #fce8e6; padding: .5em; }
+</style>
+</head>
+<body>
+<nav><h1>EPGo</h1>
+<a href="/admin/">Overview</a>
+<a href="/admin/lineups/search">Add Lineup</a>
+<a href="/admin/config">Configuration</a>
+<a href="/admin/overrides">Poster Overrides</a>
+<a href="/admin/images">Image Browser</a>
+</nav>
+<h2>{{.Title}}</h2>
+{{if .Message}}<p class="msg">{{.Message}}</p>{{end}}
+{{if .Error}}<p class="err">{{.Error}}</p>{{end}}
+{{end}}
+
+{{define "footer"}}
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" .}}
+<p>Config file: {{.ConfigFile}}<br>
+XMLTV file: {{.XMLTV}} (updated {{date .XMLTVTime}})<br>
+Account expires: {{date .Expires}}</p>
+
+<form method="post" action="/admin/refresh">
+{{if .Refreshing}}<p>A refresh is running.</p>{{else}}<button type="submit">Refresh EPG now</button>{{end}}
+</form>
+
+<h3>Lineups ({{len .Lineups}} of {{.MaxLineups}})</h3>
+<table>
+<tr><th>Lineup</th><th>Name</th><th>Modified</th><th></th></tr>
+{{range .Lineups}}
+<tr><td>{{.Lineup}}</td><td>{{.Name}}</td><td>{{.Modified}}</td>
+<td><a href="/admin/channels?lineup={{.Lineup}}">Channels</a>
+<form class="inline" method="post" action="/admin/lineups/remove" onsubmit="return confirm('Remove lineup {{.Lineup}}?')">
+<input type="hidden" name="lineup" value="{{.Lineup}}"><button type="submit">Remove</button></form></td></tr>
+{{end}}
+</table>
+
+<h3>Channels ({{len .Stations}})</h3>
+<table>
+<tr><th>ID</th><th>Name</th><th>Lineup</th><th>Number</th></tr>
+{{range sorted .Stations}}
+<tr><td>{{.ID}}</td><td>{{.Name}}</td><td>{{.Lineup}}</td><td>{{.Number}}</td></tr>
+{{end}}
+</table>
+{{template "footer" .}}{{end}}
+
+{{define "search"}}{{template "header" .}}
+<form method="get" action="/admin/lineups/search">
+<label>Country
+<select name="country">
+{{range .Countries}}<option value="{{.ShortName}}"{{if eq .ShortName $.Country}} selected{{end}}>{{.Region}}: {{.FullName}}{{if .Example}} [{{.Example}}]{{end}}</option>
+{{end}}
+</select></label>
+<label>Postal Code <input name="postalcode" value="{{.PostalCode}}" required></label>
+<button type="submit">Search</button>
+</form>
+
+{{if .Found}}
+<table>
+<tr><th>Lineup</th><th>Transport</th><th></th></tr>
+{{range .Found}}
+<tr><td>{{.Value}}</td><td>{{.Transport}}</td>
+<td><form class="inline" method="post" action="/admin/lineups/add">
+<input type="hidden" name="lineup" value="{{.Lineup}}"><button type="submit">Add</button></form></td></tr>
+{{end}}
+</table>
+{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "channels"}}{{template "header" .}}
+<form method="post" action="/admin/channels">
+<input type="hidden" name="lineup" value="{{.Lineup}}">
+<p><button type="submit">Save</button></p>
+<table>
+<tr><th></th><th>Number</th><th>ID</th><th>Name</th><th>Callsign</th><th>Language</th></tr>
+{{range .Channels}}
+<tr><td><input type="checkbox" name="station" value="{{.StationID}}"{{if .Configured}} checked{{end}}></td>
+<td>{{.Number}}</td><td>{{.StationID}}</td><td>{{.Name}}</td><td>{{.Callsign}}</td><td>{{join .Language ", "}}</td></tr>
+{{end}}
+</table>
+<p><button type="submit">Save</button></p>
+</form>
+{{template "footer" .}}{{end}}
+
+{{define "config"}}{{template "header" .}}
+<p>{{.ConfigFile}}</p>
+<form method="post" action="/admin/config">
+<textarea name="config" rows="40" spellcheck="false">{{.Text}}</textarea>
+<p><button type="submit">Save</button></p>
+</form>
+{{template "footer" .}}{{end}}
+
+{{define "images"}}{{template "header" .}}
+<form method="get" action="/admin/images">
+<label>Title, programID or series ID <input name="q" value="{{.Query}}" required autofocus></label>
+<label>Override for
+<select name="station">
+<option value="">all stations</option>
+{{range sorted .Stations}}<option value="{{.ID}}"{{if eq .ID $.Station}} selected{{end}}>{{.Name}} ({{.ID}})</option>
+{{end}}
+</select></label>
+<button type="submit">Search</button>
+</form>
+<p>Previews of images that are not cached yet are downloaded from Schedules Direct and count against the daily image limit.</p>
+
+{{range .Programs}}{{$p := .}}
+<h3>{{if .Title}}{{.Title}}{{else}}(no title){{end}} <small>{{.ProgramID}}{{if .Override}}, override: {{.Override}}{{end}}</small></h3>
+<div class="images">
+{{range .Images}}
+<figure{{if eq .ImageID $p.Override}} class="override"{{end}}>
+<a href="/admin/images/thumb/{{$p.ProgramID}}/{{.ImageID}}" target="_blank"><img src="/admin/images/thumb/{{$p.ProgramID}}/{{.ImageID}}" loading="lazy" alt="{{.ImageID}}"></a>
+<figcaption>{{.Category}}{{if .Tier}}, {{.Tier}}{{end}}<br>{{.Aspect}} {{.Width}}×{{.Height}}{{if eq .ImageID $p.Chosen}}<br><b>chosen by Poster Aspect</b>{{end}}<br>
+<code>{{.ImageID}}</code>{{if not .Cached}} (not cached){{end}}
+<form method="post" action="/admin/images/pick">
+<input type="hidden" name="programID" value="{{$p.ProgramID}}">
+<input type="hidden" name="imageID" value="{{.ImageID}}">
+<input type="hidden" name="q" value="{{$.Query}}">
+<input type="hidden" name="station" value="{{$.Station}}">
+Use for <button type="submit" name="match" value="title"{{if not $p.Title}} disabled{{end}}>Title</button>
+{{if $p.Series}}<button type="submit" name="match" value="series">Series</button>{{end}}
+<button type="submit" name="match" value="program">Program</button>
+</form></figcaption>
+</figure>
+{{end}}
+</div>
+{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "overrides"}}{{template "header" .}}
+<p>{{.ConfigFile}}: one <code>match,imageID[,stationID]</code> per line. <i>match</i> is a title (case-insensitive) or <code>program:EP012345670001</code>, <code>series:SH01234567</code>, <code>regex:(?i)^law &amp; order</code>. <i>imageID</i> is an SD image ID, <code>file:name.png</code> from the overrides folder or an http(s) URL. A stationID limits the override to that station.</p>
+<form method="post" action="/admin/overrides">
+<textarea name="overrides" rows="30" spellcheck="false">{{.Text}}</textarea>
+<p><button type="submit">Save</button></p>
+</form>
+{{template "footer" .}}{{end}}
+`
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Image browser of the admin UI: lists the SD artwork of cached programmes and
+// saves the chosen image as a poster override.
+
+const maxBrowserPrograms = 25
+
+type programImages struct {
+	ProgramID string
+	Title     string
+	Series    string // SH root; empty for movies, sports etc.
+	Chosen    string // imageID chosen with the Poster Aspect rules
+	Override  string // imageID of the override that applies now
+	Images    []programImage
+}
+
+type programImage struct {
+	Data
+	ImageID string
+	Cached  bool
+}
+
+// findProgramImages returns the cached programmes with artwork for a programID,
+// a series ID (SH01234567) or a part of the title.
+func findProgramImages(query, station string) (found []programImages, more bool) {
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return
+	}
+
+	upper := strings.ToUpper(query)
+	series := ""
+	if len(upper) == 10 {
+		series = seriesRoot(upper)
+	}
+	lower := strings.ToLower(query)
+
+	Cache.RLock()
+	for programID, m := range Cache.Metadata {
+		if len(m.Data) == 0 {
+			continue
+		}
+
+		title := ""
+		if p, ok := Cache.Program[programID]; ok && len(p.Titles) != 0 {
+			title = p.Titles[0].Title120
+		}
+
+		switch {
+		case programID == upper:
+		case len(series) != 0 && seriesRoot(programID) == series:
+		case len(title) != 0 && strings.Contains(strings.ToLower(title), lower):
+		default:
+			continue
+		}
+
+		entry := programImages{ProgramID: programID, Title: title, Series: seriesRoot(programID)}
+		for _, d := range m.Data {
+			entry.Images = append(entry.Images, programImage{Data: d, ImageID: sdImageIDFromURI(d.URI)})
+		}
+		found = append(found, entry)
+	}
+	Cache.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Title != found[j].Title {
+			return found[i].Title < found[j].Title
+		}
+		return found[i].ProgramID < found[j].ProgramID
+	})
+
+	if len(found) > maxBrowserPrograms {
+		found, more = found[:maxBrowserPrograms], true
+	}
+
+	folderImage := currentConfig().Options.Images.Path
+	if folderImage == "" {
+		folderImage = "images"
+	}
+
+	for i := range found {
+		if imageID, _, ok := Cache.GetChosenSDImage(found[i].ProgramID); ok {
+			found[i].Chosen = imageID
+		}
+		if override, ok := overrideImageForProgram(found[i].ProgramID, station); ok {
+			found[i].Override = override.ImageID
+		}
+		for j := range found[i].Images {
+			if fi, err := os.Stat(filepath.Join(folderImage, found[i].Images[j].ImageID+".jpg")); err == nil && !fi.IsDir() {
+				found[i].Images[j].Cached = true
+			}
+		}
+	}
+
+	return
+}
+
+func adminImages(w http.ResponseWriter, r *http.Request) {
+
+	var page = adminPage{
+		Title:    "Image Browser",
+		Query:    strings.TrimSpace(r.FormValue("q")),
+		Station:  normalizeStationID(r.FormValue("station")),
+		Stations: currentConfig().Station,
+	}
+
+	if len(page.Query) != 0 {
+		var more bool
+		page.Programs, more = findProgramImages(page.Query, page.Station)
+
+		switch {
+		case len(page.Programs) == 0:
+			page.Message = "No cached programme with artwork matches. The cache only holds programmes of the configured channels."
+		case more:
+			page.Message = fmt.Sprintf("Showing the first %d programmes; refine the search to see more.", maxBrowserPrograms)
+		}
+	}
+
+	adminRender(w, r, "images", page)
+}
+
+// adminImagePick saves an override for the title, program or series of a
+// programme with the chosen image.
+func adminImagePick(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	programID := strings.TrimSpace(r.FormValue("programID"))
+	imageID := strings.TrimSpace(r.FormValue("imageID"))
+	station := normalizeStationID(r.FormValue("station"))
+
+	target := "/admin/images?" + url.Values{"q": {r.FormValue("q")}, "station": {station}}.Encode()
+
+	var rec = overrideRecord{ImageID: imageID, Station: station}
+
+	switch r.FormValue("match") {
+	case overrideByProgram:
+		rec.Title = overrideByProgram + ":" + programID
+	case overrideBySeries:
+		rec.Title = overrideBySeries + ":" + seriesRoot(programID)
+	default:
+		titles := programTitles(programID)
+		if len(titles) == 0 || len(strings.TrimSpace(titles[0])) == 0 {
+			adminRedirect(w, r, target, "", fmt.Sprintf("No title cached for %s.", programID))
+			return
+		}
+		rec.Title = strings.TrimSpace(titles[0])
+		// Keep titles such as "Regex: The Show" from being read as a prefix
+		if rule, err := parseOverrideRule(rec); err == nil && rule.Kind != overrideByTitle {
+			rec.Title = overrideByTitle + ":" + rec.Title
+		}
+	}
+
+	if !isSDImageID(imageID) {
+		adminRedirect(w, r, target, "", "Invalid image ID.")
+		return
+	}
+
+	if err := setOverride(rec); err != nil {
+		adminRedirect(w, r, target, "", fmt.Sprintf("Unable to save the override: %v", err))
+		return
+	}
+
+	overridesReload()
+
+	logger.Info("Admin: poster override set", "match", rec.Title, "imageID", imageID, "station", station)
+
+	message := fmt.Sprintf("Override saved: %s → %s", rec.Title, imageID)
+	if len(station) != 0 {
+		message += " on station " + station
+	}
+	adminRedirect(w, r, target, message, "")
+}
+
+// adminImageThumb serves /admin/images/thumb/{programID}/{imageID} from the
+// image cache and downloads missing images like the proxy, but without
+// overrides and without touching the index.
+func adminImageThumb(w http.ResponseWriter, r *http.Request) {
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/images/thumb/"), "/")
+	if len(parts) != 2 || !isSDImageID(parts[1]) || strings.HasPrefix(parts[1], ".") {
+		http.Error(w, "invalid image", http.StatusBadRequest)
+		return
+	}
+	programID, imageID := parts[0], strings.TrimSuffix(parts[1], ".jpg")
+
+	folderImage := currentConfig().Options.Images.Path
+	if folderImage == "" {
+		folderImage = "images"
+	}
+	if err := os.MkdirAll(folderImage, 0755); err != nil {
+		http.Error(w, "failed to prepare image folder", http.StatusInternalServerError)
+		return
+	}
+	filePath := filepath.Join(folderImage, imageID+".jpg")
+
+	if fi, err := os.Stat(filePath); err != nil || fi.IsDir() {
+		if paused, remain := shouldBlockGlobal(); paused {
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", remain.Seconds()))
+			http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
+			return
+		}
+
+		var fetchErr *imageFetchError
+		if resultCh, isLeader := beginImageFetch(imageID); isLeader {
+			fetchErr = fetchAndCacheSDImage(programID, imageID, filePath)
+			endImageFetch(imageID, imageFetchOutcome{err: fetchErr})
+		} else {
+			fetchErr = (<-resultCh).err
+		}
+
+		if fetchErr != nil {
+			http.Error(w, fetchErr.message, fetchErr.status)
+			return
+		}
+	}
+
+	serveFileCached(w, r, filePath)
+}
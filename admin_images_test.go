@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdminImageBrowser(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	Cache.Lock()
+	originalProgram, originalMetadata := Cache.Program, Cache.Metadata
+	Cache.Unlock()
+	defer func() {
+		Cache.Lock()
+		Cache.Program, Cache.Metadata = originalProgram, originalMetadata
+		Cache.Unlock()
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+	Config.Options.Images.Path = filepath.Join(dir, "images")
+
+	cached := func(data string) (e EPGoCache) {
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		return
+	}
+
+	Cache.Lock()
+	Cache.Program = map[string]EPGoCache{
+		"EP012345670001": cached(`{"titles": [{"title120": "Regex: The Show"}]}`),
+		"MV000000010000": cached(`{"titles": [{"title120": "Heat"}]}`),
+	}
+	Cache.Metadata = map[string]EPGoCache{
+		"EP012345670001": cached(`{"data": [
+			{"uri": "assets/p301122_b_v8_aa.jpg", "category": "Banner-L1", "tier": "Series", "aspect": "2x3", "width": 240, "height": 360},
+			{"uri": "https://json.schedulesdirect.org/20141201/image/p301122_b_h6_ab.jpg", "category": "Iconic", "aspect": "16x9", "width": 1280, "height": 720}]}`),
+		"MV000000010000": cached(`{"data": [{"uri": "assets/p1234_v_v5_aa.jpg", "category": "Poster Art", "aspect": "2x3", "width": 240, "height": 360}]}`),
+	}
+	Cache.Unlock()
+
+	for _, query := range []string{"regex:", "ep012345670001", "SH01234567"} {
+		found, _ := findProgramImages(query, "")
+		if len(found) != 1 || found[0].ProgramID != "EP012345670001" || len(found[0].Images) != 2 {
+			t.Errorf("findProgramImages(%q) = %+v, want EP012345670001 with 2 images", query, found)
+			continue
+		}
+		if found[0].Images[1].ImageID != "p301122_b_h6_ab" || found[0].Images[1].Aspect != "16x9" {
+			t.Errorf("findProgramImages(%q) image = %+v", query, found[0].Images[1])
+		}
+	}
+
+	pick := func(match, imageID, station string) *httptest.ResponseRecorder {
+		form := url.Values{"programID": {"EP012345670001"}, "imageID": {imageID}, "match": {match}, "station": {station}, "q": {"regex"}}
+		req := httptest.NewRequest(http.MethodPost, "/admin/images/pick", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		adminImagePick(rec, req)
+		return rec
+	}
+
+	for _, p := range []struct{ match, imageID, station string }{
+		{"title", "p301122_b_v8_aa", ""},
+		{"series", "p301122_b_v8_aa", "20002"},
+		{"title", "p301122_b_h6_ab", ""},
+	} {
+		if rec := pick(p.match, p.imageID, p.station); rec.Code != http.StatusSeeOther || strings.Contains(rec.Header().Get("Location"), "error=") {
+			t.Fatalf("pick %+v: status = %d, location %s", p, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	data, err := os.ReadFile(overridesFilePath())
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got, want := string(data), "series:SH01234567,p301122_b_v8_aa,20002\nRegex: The Show,p301122_b_h6_ab\n"; got != want {
+		t.Errorf("overrides file = %q, want %q", got, want)
+	}
+
+	if found, _ := findProgramImages("regex", "20002"); len(found) != 1 || found[0].Override != "p301122_b_v8_aa" {
+		t.Errorf("station override not shown: %+v", found)
+	}
+	if found, _ := findProgramImages("regex", ""); len(found) != 1 || found[0].Override != "p301122_b_h6_ab" {
+		t.Errorf("title override not shown: %+v", found)
+	}
+
+	rec := httptest.NewRecorder()
+	adminImages(rec, httptest.NewRequest(http.MethodGet, "/admin/images?q=heat", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/admin/images/thumb/MV000000010000/p1234_v_v5_aa") {
+		t.Errorf("image browser page: status = %d, thumbnail missing", rec.Code)
+	}
+}
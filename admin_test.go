@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminAuth(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Server.Admin.Username = "admin"
+	Config.Server.Admin.Password = "secret"
+
+	handler := adminAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		user     string
+		pass     string
+		origin   string
+		password string
+		want     int
+	}{
+		{name: "no credentials", method: http.MethodGet, password: "secret", want: http.StatusUnauthorized},
+		{name: "wrong password", method: http.MethodGet, user: "admin", pass: "nope", password: "secret", want: http.StatusUnauthorized},
+		{name: "valid", method: http.MethodGet, user: "admin", pass: "secret", password: "secret", want: http.StatusOK},
+		{name: "empty configured password", method: http.MethodGet, user: "admin", pass: "", password: "", want: http.StatusUnauthorized},
+		{name: "same-origin post", method: http.MethodPost, user: "admin", pass: "secret", origin: "http://example.com", password: "secret", want: http.StatusOK},
+		{name: "cross-origin post", method: http.MethodPost, user: "admin", pass: "secret", origin: "http://evil.test", password: "secret", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config.Server.Admin.Password = tt.password
+
+			req := httptest.NewRequest(tt.method, "http://example.com/admin/", nil)
+			if len(tt.user) != 0 {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			if len(tt.origin) != 0 {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminOverrides(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		form := url.Values{"overrides": {body}}
+		req := httptest.NewRequest(http.MethodPost, "/admin/overrides", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		adminOverrides(rec, req)
+		return rec
+	}
+
+	// Invalid lines are reported and nothing is written
+	rec := post("Jeopardy!,p12345_b_v2\nbroken line\n")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "line 2") {
+		t.Fatalf("invalid overrides: status = %d, body does not name line 2", rec.Code)
+	}
+	if _, err := os.Stat(overridesFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("overrides file written despite errors: %v", err)
+	}
+
+	rec = post("Jeopardy!,p12345_b_v2\r\nThe Simpsons, p67890_b_h6")
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("valid overrides: status = %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+
+	data, err := os.ReadFile(overridesFilePath())
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got, want := string(data), "Jeopardy!,p12345_b_v2\nThe Simpsons, p67890_b_h6\n"; got != want {
+		t.Errorf("overrides file = %q, want %q", got, want)
+	}
+
+	if id, ok := overrideImageForTitle("the simpsons"); !ok || id != "p67890_b_h6" {
+		t.Errorf("overrideImageForTitle() = %q, %v; want reloaded override", id, ok)
+	}
+
+	// A save waits for a running setOverride instead of interleaving with it
+	overridesFileMu.Lock()
+	done := make(chan int)
+	go func() { done <- post("Heat,p1234_v_v5\n").Code }()
+	select {
+	case code := <-done:
+		overridesFileMu.Unlock()
+		t.Fatalf("save finished (status %d) while overridesFileMu was held", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+	overridesFileMu.Unlock()
+	if code := <-done; code != http.StatusSeeOther {
+		t.Errorf("save after unlock: status = %d", code)
+	}
+}
+
+func TestAdminLineupRejectsInvalidID(t *testing.T) {
+	for _, lineup := range []string{"USA-NY12345-X/../../token", "USA-NY12345-X?x=1", "usa-ny12345-x", "DEU"} {
+		for _, handler := range []http.HandlerFunc{adminLineupChange("add"), adminLineupChange("remove"), adminChannels} {
+			form := url.Values{"lineup": {lineup}}
+			req := httptest.NewRequest(http.MethodPost, "/admin/lineups/add", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if location := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || !strings.Contains(location, "error=Invalid+lineup+ID") {
+				t.Errorf("lineup %q: status = %d, location %q", lineup, rec.Code, location)
+			}
+		}
+	}
+
+	for _, lineup := range []string{"USA-NY12345-X", "DEU-1000097-DEFAULT", "USA-OTA-10001"} {
+		if !isLineupID(lineup) {
+			t.Errorf("isLineupID(%q) = false", lineup)
+		}
+	}
+}
+
+func TestAdminConfigRejectsInvalidYAML(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.File = filepath.Join(dir, "config")
+	data := []byte("Options:\n  Schedule Days: 7\n")
+	if err := os.WriteFile(Config.File+".yaml", data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	for _, body := range []string{"Options: [", "Options:\n  Refresh Schedule: \"every day\"\n"} {
+		form := url.Values{"config": {body}}
+		req := httptest.NewRequest(http.MethodPost, "/admin/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		adminConfig(rec, req)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `class="err"`) {
+			t.Errorf("config %q: status = %d, want the form with an error", body, rec.Code)
+		}
+	}
+
+	got, err := os.ReadFile(Config.File + ".yaml")
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("config file changed to %q", got)
+	}
+}
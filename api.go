@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"epgo/internal/atomicfile"
+)
+
+// REST API on /api/ (Server: API Token) for scripts and monitoring:
+//
+//	POST   /api/refresh             start an EPG refresh
+//	GET    /api/status              last refresh, SD account expiry, image fetch pause
+//	POST   /api/pause/clear         clear the global image fetch pause
+//	GET    /api/overrides           list the poster overrides
+//	PUT    /api/overrides           replace the poster overrides
+//	DELETE /api/cache/images/{id}   remove a cached image
+
+type apiStatus struct {
+	Version     string          `json:"version"`
+	Refreshing  bool            `json:"refreshing"`
+	LastRefresh *apiRefresh     `json:"lastRefresh,omitempty"`
+	Account     apiAccount      `json:"account"`
+	XMLTV       apiXMLTV        `json:"xmltv"`
+	Pause       apiImagesPaused `json:"imageFetchPause"`
+}
+
+type apiRefresh struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration float64   `json:"durationSeconds"`
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type apiAccount struct {
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type apiXMLTV struct {
+	File     string     `json:"file"`
+	Modified *time.Time `json:"modified,omitempty"`
+}
+
+type apiImagesPaused struct {
+	Active bool       `json:"active"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+type apiMessage struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// apiHandler returns the REST API behind the API token.
+func apiHandler() http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/refresh", apiRefreshHandler)
+	mux.HandleFunc("/api/status", apiStatusHandler)
+	mux.HandleFunc("/api/pause/clear", apiPauseClearHandler)
+	mux.HandleFunc("/api/overrides", apiOverridesHandler)
+	mux.HandleFunc("/api/cache/images/", apiCacheImageHandler)
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, apiMessage{Error: "unknown endpoint"})
+	})
+
+	return apiAuth(mux)
+}
+
+// apiAuth accepts "Authorization: Bearer <token>" or "X-API-Token: <token>".
+// Without Server: API Token the API is disabled and answers 404.
+func apiAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		token := currentConfig().Server.APIToken
+		if len(token) == 0 {
+			writeJSON(w, http.StatusNotFound, apiMessage{Error: "API disabled; set Server: API Token"})
+			return
+		}
+
+		given := r.Header.Get("X-API-Token")
+		if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			given = strings.TrimSpace(auth[7:])
+		}
+
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="EPGo API"`)
+			writeJSON(w, http.StatusUnauthorized, apiMessage{Error: "invalid or missing API token"})
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Warn("API: unable to write the response", "error", err)
+	}
+}
+
+// allowMethod answers 405 unless the request uses one of the methods.
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, apiMessage{Error: "method not allowed"})
+	return false
+}
+
+func apiRefreshHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if !startRefresh(configFileName()) {
+		writeJSON(w, http.StatusConflict, apiMessage{Error: "a refresh is already running"})
+		return
+	}
+
+	logger.Info("API: EPG refresh started")
+	writeJSON(w, http.StatusAccepted, apiMessage{Message: "refresh started"})
+}
+
+func apiStatusHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !allowMethod(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	var status = apiStatus{
+		Version:    Version,
+		Refreshing: refreshRunning(),
+		XMLTV:      apiXMLTV{File: currentConfig().Files.XMLTV},
+	}
+
+	last := lastRefreshResult()
+	if !last.Finished.IsZero() {
+		status.LastRefresh = &apiRefresh{
+			Started:  last.Started.UTC(),
+			Finished: last.Finished.UTC(),
+			Duration: last.Finished.Sub(last.Started).Seconds(),
+			OK:       last.Err == nil,
+		}
+		if last.Err != nil {
+			status.LastRefresh.Error = last.Err.Error()
+		}
+	}
+
+	if !last.AccountExpires.IsZero() {
+		expires := last.AccountExpires.UTC()
+		status.Account.Expires = &expires
+	}
+
+	if fi, err := os.Stat(status.XMLTV.File); err == nil {
+		modified := fi.ModTime().UTC()
+		status.XMLTV.Modified = &modified
+	}
+
+	if until, reason, active := globalPause(); active {
+		status.Pause = apiImagesPaused{Active: true, Until: &until, Reason: reason}
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func apiPauseClearHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	clearGlobalPause()
+	writeJSON(w, http.StatusOK, apiMessage{Message: "image fetch pause cleared"})
+}
+
+func apiOverridesHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !allowMethod(w, r, http.MethodGet, http.MethodHead, http.MethodPut) {
+		return
+	}
+
+	path := overridesFilePath()
+
+	if r.Method != http.MethodPut {
+		var records = make([]overrideRecord, 0)
+
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			writeJSON(w, http.StatusInternalServerError, apiMessage{Error: err.Error()})
+			return
+		}
+		if parsed, _ := parseOverrides(data); parsed != nil {
+			records = parsed
+		}
+
+		writeJSON(w, http.StatusOK, records)
+		return
+	}
+
+	var records []overrideRecord
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&records); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiMessage{Error: "expected a JSON array of {\"title\", \"imageID\", \"station\"}: " + err.Error()})
+		return
+	}
+
+	for i := range records {
+		records[i].Title = strings.TrimSpace(records[i].Title)
+		records[i].ImageID = strings.TrimSpace(records[i].ImageID)
+		records[i].Station = strings.TrimSpace(records[i].Station)
+		if _, err := parseOverrideRule(records[i]); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiMessage{Error: fmt.Sprintf("override %d: %v", i+1, err)})
+			return
+		}
+	}
+
+	// setOverride (admin image browser) rewrites the file as well
+	overridesFileMu.Lock()
+	err := atomicfile.WriteFile(path, formatOverrides(records), 0644)
+	overridesFileMu.Unlock()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiMessage{Error: err.Error()})
+		return
+	}
+
+	overridesReload()
+
+	logger.Info("API: poster overrides replaced", "path", path, "count", len(records))
+	writeJSON(w, http.StatusOK, records)
+}
+
+func apiCacheImageHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !allowMethod(w, r, http.MethodDelete) {
+		return
+	}
+
+	imageID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/cache/images/"), ".jpg")
+	if !isSDImageID(imageID) || strings.HasPrefix(imageID, ".") {
+		writeJSON(w, http.StatusBadRequest, apiMessage{Error: "invalid image ID"})
+		return
+	}
+
+	folderImage := currentConfig().Options.Images.Path
+	if folderImage == "" {
+		folderImage = "images"
+	}
+	filePath := filepath.Join(folderImage, imageID+".jpg")
+
+	if err := os.Remove(filePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSON(w, http.StatusNotFound, apiMessage{Error: "image not in cache"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiMessage{Error: err.Error()})
+		return
+	}
+
+	if err := indexDeleteImageIDs([]string{imageID}); err != nil {
+		logger.Warn("API: failed to prune index for deleted image", "imageID", imageID, "error", err)
+	}
+
+	logger.Info("API: cached image deleted", "imageID", imageID, "path", filePath)
+	writeJSON(w, http.StatusOK, apiMessage{Message: "image deleted"})
+}
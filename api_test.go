@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIAuth(t *testing.T) {
+	original := Config
+	defer func() { Config = original }()
+
+	handler := apiAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		value  string
+		want   int
+	}{
+		{name: "bearer", token: "s3cret", header: "Authorization", value: "Bearer s3cret", want: http.StatusOK},
+		{name: "x-api-token", token: "s3cret", header: "X-API-Token", value: "s3cret", want: http.StatusOK},
+		{name: "wrong token", token: "s3cret", header: "Authorization", value: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "missing token", token: "s3cret", want: http.StatusUnauthorized},
+		{name: "api disabled", token: "", header: "X-API-Token", value: "", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config.Server.APIToken = tt.token
+
+			req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+			if len(tt.header) != 0 {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPITokenAfterStartup(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Server.APIToken = ""
+	mux := newServerMux(&Config, t.TempDir(), "8080")
+
+	status := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := status(); got != http.StatusNotFound {
+		t.Errorf("status without token = %d, want %d", got, http.StatusNotFound)
+	}
+
+	// A reload sets the token; the routes registered at startup pick it up
+	Config.Server.APIToken = "s3cret"
+	if got := status(); got != http.StatusOK {
+		t.Errorf("status after setting the token = %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestAPIOverrides(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Files.Cache = filepath.Join(t.TempDir(), "config_cache.json")
+
+	body := `[{"title": "Law & Order: Special Victims Unit", "imageID": "p301122_b_v8"}, {"title": "Jeopardy, The Game", "imageID": "p12345_b_v2"}]`
+	req := httptest.NewRequest(http.MethodPut, "/api/overrides", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	apiOverridesHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if id, ok := overrideImageForTitle("jeopardy, the game"); !ok || id != "p12345_b_v2" {
+		t.Errorf("overrideImageForTitle() = %q, %v; want the new override", id, ok)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/overrides", nil)
+	rec = httptest.NewRecorder()
+	apiOverridesHandler(rec, req)
+
+	var got []overrideRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET body: %v", err)
+	}
+	if len(got) != 2 || got[0].Title != "Law & Order: Special Victims Unit" || got[1].ImageID != "p12345_b_v2" {
+		t.Errorf("GET overrides = %+v", got)
+	}
+
+	// A PUT waits for a running setOverride instead of interleaving with it
+	overridesFileMu.Lock()
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		apiOverridesHandler(rec, httptest.NewRequest(http.MethodPut, "/api/overrides", strings.NewReader(`[{"title": "Heat", "imageID": "p1234_v_v5"}]`)))
+		done <- rec.Code
+	}()
+	select {
+	case code := <-done:
+		overridesFileMu.Unlock()
+		t.Fatalf("PUT finished (status %d) while overridesFileMu was held", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+	overridesFileMu.Unlock()
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("PUT after unlock: status = %d", code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/overrides", strings.NewReader(`[{"title": "No image"}]`))
+	rec = httptest.NewRecorder()
+	apiOverridesHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT without imageID: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPICacheImageDelete(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.Options.Images.Path = dir
+	if err := os.WriteFile(filepath.Join(dir, "p12345_b_v2.jpg"), []byte("jpg"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "wrong method", method: http.MethodGet, path: "/api/cache/images/p12345_b_v2", want: http.StatusMethodNotAllowed},
+		{name: "path traversal", method: http.MethodDelete, path: "/api/cache/images/..", want: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/api/cache/images/p12345_b_v2.jpg", want: http.StatusOK},
+		{name: "already deleted", method: http.MethodDelete, path: "/api/cache/images/p12345_b_v2", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rec := httptest.NewRecorder()
+		apiCacheImageHandler(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestAPIStatusPause(t *testing.T) {
+	originalLogger := logger
+	defer func() {
+		clearGlobalPause()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	setGlobalPauseUntil(time.Now().Add(time.Hour), "test")
+
+	status := func() apiStatus {
+		rec := httptest.NewRecorder()
+		apiStatusHandler(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+		var s apiStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+			t.Fatalf("status body: %v", err)
+		}
+		return s
+	}
+
+	if s := status(); !s.Pause.Active || s.Pause.Reason != "test" {
+		t.Errorf("pause = %+v, want active with reason", s.Pause)
+	}
+
+	rec := httptest.NewRecorder()
+	apiPauseClearHandler(rec, httptest.NewRequest(http.MethodPost, "/api/pause/clear", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause/clear status = %d", rec.Code)
+	}
+
+	if s := status(); s.Pause.Active {
+		t.Errorf("pause still active after clear: %+v", s.Pause)
+	}
+}
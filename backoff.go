@@ -10,6 +10,7 @@ import (
 var (
 	imageFetchPauseMu    sync.RWMutex
 	imageFetchPauseUntil time.Time // UTC instant when pause ends; zero => no pause
+	imageFetchPauseWhy   string
 )
 
 // shouldBlockGlobal reports whether a global pause is active, and the remaining duration.
@@ -36,6 +37,7 @@ func setGlobalPauseUntil(until time.Time, reason string) {
 	// Only extend, never shorten, to avoid flapping under load.
 	if until.After(imageFetchPauseUntil) {
 		imageFetchPauseUntil = until
+		imageFetchPauseWhy = reason
 		logger.Warn("Proxy: global image fetch paused", "until_utc", until, "reason", reason)
 	}
 }
@@ -44,10 +46,21 @@ func setGlobalPauseUntil(until time.Time, reason string) {
 func clearGlobalPause() {
 	imageFetchPauseMu.Lock()
 	imageFetchPauseUntil = time.Time{}
+	imageFetchPauseWhy = ""
 	imageFetchPauseMu.Unlock()
 	logger.Info("Proxy: global image fetch pause cleared")
 }
 
+// globalPause returns the end and the reason of the global pause, if one is active.
+func globalPause() (until time.Time, reason string, active bool) {
+	imageFetchPauseMu.RLock()
+	defer imageFetchPauseMu.RUnlock()
+	if imageFetchPauseUntil.IsZero() || !time.Now().UTC().Before(imageFetchPauseUntil) {
+		return time.Time{}, "", false
+	}
+	return imageFetchPauseUntil, imageFetchPauseWhy, true
+}
+
 // nextUTCMidnightPlus returns the next UTC midnight after 'ref' plus 'mins' minutes.
 func nextUTCMidnightPlus(ref time.Time, mins int) time.Time {
 	ref = ref.UTC()
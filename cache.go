@@ -7,8 +7,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+
+	"epgo/internal/atomicfile"
 )
 
 // Cache : Cache file
@@ -72,10 +75,17 @@ func aspectRank(aspect string) int {
 // Init : Inti cache
 func (c *cache) Init() {
 
+	c.Lock()
+	defer c.Unlock()
+
 	if c.Schedule == nil {
 		c.Schedule = make(map[string][]EPGoCache)
 	}
 
+	if c.ScheduleMD5 == nil {
+		c.ScheduleMD5 = make(map[string]map[string]string)
+	}
+
 	c.Channel = make(map[string]EPGoCache)
 
 	if c.Program == nil {
@@ -111,6 +121,7 @@ func (c *cache) AddStations(data *[]byte, lineup string) {
 	}
 
 	var channelIDs = Config.GetChannelList(lineup)
+	var numbers = lineupChannelNumbers(sdData.Map)
 
 	for _, sd := range sdData.Stations {
 
@@ -120,6 +131,7 @@ func (c *cache) AddStations(data *[]byte, lineup string) {
 			epgoCache.Name = sd.Name
 			epgoCache.Callsign = sd.Callsign
 			epgoCache.Affiliate = sd.Affiliate
+			epgoCache.ChannelNumber = numbers[sd.StationID]
 			epgoCache.BroadcastLanguage = sd.BroadcastLanguage
 			epgoCache.Logo = sd.Logo
 
@@ -151,6 +163,31 @@ func (c *cache) AddSchedule(data *[]byte) {
 			c.Schedule[sd.StationID] = []EPGoCache{}
 		}
 
+		// A schedule response replaces whole days: drop the cached entries of
+		// every day it covers before appending the new ones.
+		var replaced = make(map[string]bool)
+		if len(sd.Metadata.StartDate) != 0 {
+			replaced[sd.Metadata.StartDate] = true
+		}
+		for _, p := range sd.Programs {
+			replaced[p.AirDateTime.UTC().Format("2006-01-02")] = true
+		}
+
+		var kept = make([]EPGoCache, 0, len(c.Schedule[sd.StationID]))
+		for _, s := range c.Schedule[sd.StationID] {
+			if !replaced[s.AirDateTime.UTC().Format("2006-01-02")] {
+				kept = append(kept, s)
+			}
+		}
+		c.Schedule[sd.StationID] = kept
+
+		if len(sd.Metadata.StartDate) != 0 && len(sd.Metadata.Md5) != 0 {
+			if _, ok := c.ScheduleMD5[sd.StationID]; !ok {
+				c.ScheduleMD5[sd.StationID] = make(map[string]string)
+			}
+			c.ScheduleMD5[sd.StationID][sd.Metadata.StartDate] = sd.Metadata.Md5
+		}
+
 		for _, p := range sd.Programs {
 
 			epgoCache.AirDateTime = p.AirDateTime
@@ -167,10 +204,65 @@ func (c *cache) AddSchedule(data *[]byte) {
 
 		}
 
+		sort.SliceStable(c.Schedule[sd.StationID], func(i, j int) bool {
+			return c.Schedule[sd.StationID][i].AirDateTime.Before(c.Schedule[sd.StationID][j].AirDateTime)
+		})
+
 	}
 
 }
 
+// PruneSchedule drops cached schedule days outside of days and stations that are
+// no longer configured, so unchanged days can be kept between runs.
+func (c *cache) PruneSchedule(stationIDs, days []string) {
+
+	c.Lock()
+	defer c.Unlock()
+
+	for stationID, schedule := range c.Schedule {
+
+		if ContainsString(stationIDs, stationID) == -1 {
+			delete(c.Schedule, stationID)
+			continue
+		}
+
+		var kept = make([]EPGoCache, 0, len(schedule))
+		for _, s := range schedule {
+			if ContainsString(days, s.AirDateTime.UTC().Format("2006-01-02")) != -1 {
+				kept = append(kept, s)
+			}
+		}
+		c.Schedule[stationID] = kept
+
+	}
+
+	for stationID, dates := range c.ScheduleMD5 {
+
+		if ContainsString(stationIDs, stationID) == -1 {
+			delete(c.ScheduleMD5, stationID)
+			continue
+		}
+
+		for date := range dates {
+			if ContainsString(days, date) == -1 {
+				delete(dates, date)
+			}
+		}
+
+	}
+
+}
+
+// GetScheduleMD5 returns the md5 of a cached schedule day.
+func (c *cache) GetScheduleMD5(stationID, date string) (md5 string, ok bool) {
+
+	c.RLock()
+	defer c.RUnlock()
+
+	md5, ok = c.ScheduleMD5[stationID][date]
+	return
+}
+
 func (c *cache) AddProgram(gzip *[]byte, wg *sync.WaitGroup) {
 
 	c.Lock()
@@ -197,6 +289,7 @@ func (c *cache) AddProgram(gzip *[]byte, wg *sync.WaitGroup) {
 
 	for _, sd := range sdData {
 
+		epgoCache.Md5 = sd.Md5
 		epgoCache.Descriptions = sd.Descriptions
 		epgoCache.EpisodeTitle150 = sd.EpisodeTitle150
 		epgoCache.Genres = sd.Genres
@@ -240,6 +333,7 @@ func (c *cache) AddMetadata(gzip *[]byte, wg *sync.WaitGroup) {
 	var tmp = make([]interface{}, 0)
 
 	var epgoCache EPGoCache
+	var downloadErrors = currentConfig().Options.SDDownloadErrors
 
 	err = json.Unmarshal(b, &tmp)
 	if err != nil {
@@ -259,13 +353,13 @@ func (c *cache) AddMetadata(gzip *[]byte, wg *sync.WaitGroup) {
 			err = json.Unmarshal(jsonByte, &sdError)
 			if err == nil {
 
-				if Config.Options.SDDownloadErrors {
+				if downloadErrors {
 					err = fmt.Errorf("%s [SD API Error Code: %d] Program ID: %s", sdError.Data.Message, sdError.Data.Code, sdError.ProgramID)
 					logger.Error("unable to unmarshal the JSON", "error", err)
 				}
 
 			} else {
-				if Config.Options.SDDownloadErrors {
+				if downloadErrors {
 					logger.Error("unable to unmarshal the JSON", "error", err)
 				}
 			}
@@ -282,6 +376,9 @@ func (c *cache) AddMetadata(gzip *[]byte, wg *sync.WaitGroup) {
 
 func (c *cache) GetAllProgramIDs() (programIDs []string) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	for _, channel := range c.Schedule {
 
 		for _, schedule := range channel {
@@ -297,16 +394,27 @@ func (c *cache) GetAllProgramIDs() (programIDs []string) {
 	return
 }
 
+// GetRequiredProgramIDs returns the programs that are missing from the cache or
+// whose MD5 in the schedule differs from the cached program (updated by SD).
 func (c *cache) GetRequiredProgramIDs() (programIDs []string) {
 
-	var allProgramIDs = c.GetAllProgramIDs()
+	c.RLock()
+	defer c.RUnlock()
 
-	for _, id := range allProgramIDs {
+	var seen = make(map[string]bool)
 
-		if _, ok := c.Program[id]; !ok {
+	for _, channel := range c.Schedule {
 
-			if ContainsString(programIDs, id) == -1 {
-				programIDs = append(programIDs, id)
+		for _, schedule := range channel {
+
+			if seen[schedule.ProgramID] {
+				continue
+			}
+			seen[schedule.ProgramID] = true
+
+			p, ok := c.Program[schedule.ProgramID]
+			if !ok || (len(schedule.Md5) != 0 && p.Md5 != schedule.Md5) {
+				programIDs = append(programIDs, schedule.ProgramID)
 			}
 
 		}
@@ -316,8 +424,35 @@ func (c *cache) GetRequiredProgramIDs() (programIDs []string) {
 	return
 }
 
+// GetChangedProgramIDs returns the subset of programIDs that is already cached
+// (and therefore re-downloaded because its MD5 changed).
+func (c *cache) GetChangedProgramIDs(programIDs []string) (changed []string) {
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, id := range programIDs {
+		if _, ok := c.Program[id]; ok {
+			changed = append(changed, id)
+		}
+	}
+
+	return
+}
+
+// hasMetadata reports whether image metadata for programID is cached.
+func (c *cache) hasMetadata(programID string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.Metadata[programID]
+	return ok
+}
+
 func (c *cache) GetRequiredMetaIDs() (metaIDs []string) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	for id := range c.Program {
 
 		if len(id) > 10 {
@@ -335,12 +470,19 @@ func (c *cache) GetRequiredMetaIDs() (metaIDs []string) {
 // GetChosenSDImage returns imageID + Data for the image chosen with strict category logic
 // and your aspect preference. If none qualifies, returns ok=false (so TMDb can take over).
 func (c *cache) GetChosenSDImage(programID string) (imageID string, chosen Data, ok bool) {
+	return c.GetChosenSDImageForAspect(programID, currentConfig().Options.Images.PosterAspect)
+}
+
+// GetChosenSDImageForAspect is GetChosenSDImage with an explicit Poster Aspect (output profiles).
+func (c *cache) GetChosenSDImageForAspect(programID, aspect string) (imageID string, chosen Data, ok bool) {
+	c.RLock()
 	m, ok := c.Metadata[programID]
+	c.RUnlock()
 	if !ok || len(m.Data) == 0 {
 		return "", Data{}, false
 	}
 
-	desired := strings.TrimSpace(Config.Options.Images.PosterAspect)
+	desired := strings.TrimSpace(aspect)
 
 	// 1) Filter to allowed categories only
 	filtered := make([]Data, 0, len(m.Data))
@@ -393,8 +535,16 @@ func (c *cache) GetChosenSDImage(programID string) (imageID string, chosen Data,
 
 // Legacy API used when not in proxy pin mode (kept compatible)
 func (c *cache) GetIcon(id string) (i []Icon) {
+	return c.GetIconForAspect(id, Config.Options.Images.PosterAspect)
+}
+
+// GetIconForAspect is GetIcon with an explicit Poster Aspect (output profiles).
+func (c *cache) GetIconForAspect(id, aspect string) (i []Icon) {
+	c.RLock()
+	defer c.RUnlock()
+
 	if m, ok := c.Metadata[id]; ok {
-		desired := strings.TrimSpace(Config.Options.Images.PosterAspect)
+		desired := strings.TrimSpace(aspect)
 
 		// allowed categories only
 		filtered := make([]Data, 0, len(m.Data))
@@ -465,9 +615,23 @@ func (c *cache) Open() (err error) {
 		return nil
 	}
 
+	// The proxy may read the maps while a scheduled refresh reloads them.
+	c.Lock()
 	err = json.Unmarshal(data, &c)
 	if err != nil {
-		return
+		c.Channel, c.Program, c.Metadata, c.Schedule, c.ScheduleMD5 = nil, nil, nil, nil, nil
+	}
+	c.Unlock()
+
+	if err != nil {
+		// A truncated or corrupt cache is moved aside and rebuilt from Schedules Direct
+		// instead of failing the run.
+		corrupt := Config.Files.Cache + ".corrupt"
+		logger.Warn("Cache file is corrupt; rebuilding", "filename", Config.Files.Cache, "moved_to", corrupt, "error", err)
+		_ = os.Rename(Config.Files.Cache, corrupt)
+
+		c.Init()
+		return c.Save()
 	}
 
 	return
@@ -483,7 +647,7 @@ func (c *cache) Save() (err error) {
 		return err
 	}
 
-	err = os.WriteFile(Config.Files.Cache, data, 0644)
+	err = atomicfile.WriteFile(currentConfig().Files.Cache, data, 0644)
 	if err != nil {
 		return
 	}
@@ -498,6 +662,8 @@ func (c *cache) CleanUp() {
 
 	var programIDs = c.GetAllProgramIDs()
 
+	c.Lock()
+
 	for id := range c.Program {
 
 		if ContainsString(programIDs, id) == -1 {
@@ -510,8 +676,10 @@ func (c *cache) CleanUp() {
 
 	}
 
+	// Schedule and ScheduleMD5 are kept for the next incremental download.
 	c.Channel = make(map[string]EPGoCache)
-	c.Schedule = make(map[string][]EPGoCache)
+
+	c.Unlock()
 
 	logger.Info("Clean up Cache", "count", count)
 
@@ -525,6 +693,9 @@ func (c *cache) CleanUp() {
 // Get data from cache
 func (c *cache) GetTitle(id, lang string) (t []Title) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	if p, ok := c.Program[id]; ok {
 
 		var title Title
@@ -549,6 +720,9 @@ func (c *cache) GetTitle(id, lang string) (t []Title) {
 
 func (c *cache) GetSubTitle(id, lang string) (s SubTitle) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	if p, ok := c.Program[id]; ok {
 
 		if len(p.EpisodeTitle150) != 0 {
@@ -574,6 +748,9 @@ func (c *cache) GetSubTitle(id, lang string) (s SubTitle) {
 
 func (c *cache) GetDescs(id, subTitle string) (de []Desc) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	if p, ok := c.Program[id]; ok {
 
 		d := p.Descriptions
@@ -608,40 +785,39 @@ func (c *cache) GetDescs(id, subTitle string) (de []Desc) {
 
 func (c *cache) GetCredits(id string) (cr Credits) {
 
-	if Config.Options.Credits {
+	c.RLock()
+	defer c.RUnlock()
 
-		if p, ok := c.Program[id]; ok {
+	if p, ok := c.Program[id]; ok {
 
-			// Crew
-			for _, crew := range p.Crew {
+		// Crew
+		for _, crew := range p.Crew {
 
-				switch crew.Role {
+			switch crew.Role {
 
-				case "Director":
-					cr.Director = append(cr.Director, Director{Value: crew.Name})
+			case "Director":
+				cr.Director = append(cr.Director, Director{Value: crew.Name})
 
-				case "Producer":
-					cr.Producer = append(cr.Producer, Producer{Value: crew.Name})
+			case "Producer":
+				cr.Producer = append(cr.Producer, Producer{Value: crew.Name})
 
-				case "Presenter":
-					cr.Presenter = append(cr.Presenter, Presenter{Value: crew.Name})
+			case "Presenter":
+				cr.Presenter = append(cr.Presenter, Presenter{Value: crew.Name})
 
-				case "Writer":
-					cr.Writer = append(cr.Writer, Writer{Value: crew.Name})
-
-				}
+			case "Writer":
+				cr.Writer = append(cr.Writer, Writer{Value: crew.Name})
 
 			}
 
-			// Cast
-			for _, cast := range p.Cast {
+		}
 
-				switch cast.Role {
+		// Cast
+		for _, cast := range p.Cast {
 
-				case "Actor":
-					cr.Actor = append(cr.Actor, Actor{Value: cast.Name, Role: cast.CharacterName})
+			switch cast.Role {
 
-				}
+			case "Actor":
+				cr.Actor = append(cr.Actor, Actor{Value: cast.Name, Role: cast.CharacterName})
 
 			}
 
@@ -654,6 +830,9 @@ func (c *cache) GetCredits(id string) (cr Credits) {
 
 func (c *cache) GetCategory(id string) (ca []Category) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	if p, ok := c.Program[id]; ok {
 
 		for _, g := range p.Genres {
@@ -673,6 +852,9 @@ func (c *cache) GetCategory(id string) (ca []Category) {
 
 func (c *cache) GetEpisodeNum(id string) (ep []EpisodeNum) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	var seaseon, episode int
 
 	if p, ok := c.Program[id]; ok {
@@ -745,6 +927,9 @@ func (c *cache) GetEpisodeNum(id string) (ep []EpisodeNum) {
 
 func (c *cache) GetPreviouslyShown(id string) (prev *PreviouslyShown) {
 
+	c.RLock()
+	defer c.RUnlock()
+
 	prev = &PreviouslyShown{}
 
 	if p, ok := c.Program[id]; ok {
@@ -756,9 +941,8 @@ func (c *cache) GetPreviouslyShown(id string) (prev *PreviouslyShown) {
 
 func (c *cache) GetRating(id, countryCode string) (ra []Rating) {
 
-	if !Config.Options.Rating.Guidelines {
-		return
-	}
+	c.RLock()
+	defer c.RUnlock()
 
 	var add = func(code, body, country string) {
 
@@ -861,16 +1045,20 @@ func downloadImage(imageURL, programID string) (string, error) {
 	}
 	defer resp.Body.Close()
 
-	out, err := os.Create(filePath)
+	out, err := atomicfile.Create(filePath, 0644)
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %w", err)
 	}
-	defer out.Close()
+	defer out.Abort()
 
 	_, err = io.Copy(out, resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("failed to save image: %w", err)
 	}
 
+	if err = out.Commit(); err != nil {
+		return "", fmt.Errorf("failed to save image: %w", err)
+	}
+
 	return filePath, nil
 }
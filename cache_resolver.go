@@ -8,12 +8,14 @@ import "strings"
 // - Score = tierRank*100 + catRank*10 + aspectRank; ties by larger width
 // - No generic fallback (returns false if no qualifying image)
 func (c *cache) resolveSDImageForProgram(programID string) (Data, bool) {
+	c.RLock()
 	m, ok := c.Metadata[programID]
+	c.RUnlock()
 	if !ok || len(m.Data) == 0 {
 		return Data{}, false
 	}
 
-	desired := strings.TrimSpace(Config.Options.Images.PosterAspect)
+	desired := strings.TrimSpace(currentConfig().Options.Images.PosterAspect)
 
 	// allowed categories only
 	filtered := make([]Data, 0, len(m.Data))
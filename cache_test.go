@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// scheduleIDs returns the programIDs of a cached station schedule in order.
+func scheduleIDs(c *cache, stationID string) (ids []string) {
+	for _, s := range c.Schedule[stationID] {
+		ids = append(ids, s.ProgramID)
+	}
+	return
+}
+
+func TestAddScheduleReplacesDays(t *testing.T) {
+	originalLogger := logger
+	defer func() { logger = originalLogger }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var c cache
+	c.Init()
+
+	data := []byte(`[
+		{"stationID": "90447", "metadata": {"startDate": "2024-03-10", "md5": "day1"}, "programs": [
+			{"programID": "EP000000010001", "airDateTime": "2024-03-10T20:00:00Z", "duration": 3600},
+			{"programID": "EP000000010002", "airDateTime": "2024-03-10T21:00:00Z", "duration": 3600}]},
+		{"stationID": "90447", "metadata": {"startDate": "2024-03-11", "md5": "day2"}, "programs": [
+			{"programID": "EP000000020001", "airDateTime": "2024-03-11T20:00:00Z", "duration": 3600}]}]`)
+	c.AddSchedule(&data)
+
+	// SD changed the second day only
+	changed := []byte(`[
+		{"stationID": "90447", "metadata": {"startDate": "2024-03-11", "md5": "day2-new"}, "programs": [
+			{"programID": "EP000000020002", "airDateTime": "2024-03-11T19:30:00Z", "duration": 1800},
+			{"programID": "EP000000020003", "airDateTime": "2024-03-11T20:00:00Z", "duration": 3600}]}]`)
+	c.AddSchedule(&changed)
+
+	want := []string{"EP000000010001", "EP000000010002", "EP000000020002", "EP000000020003"}
+	if got := scheduleIDs(&c, "90447"); !reflect.DeepEqual(got, want) {
+		t.Errorf("schedule = %v, want %v", got, want)
+	}
+
+	wantMD5 := map[string]string{"2024-03-10": "day1", "2024-03-11": "day2-new"}
+	if got := c.ScheduleMD5["90447"]; !reflect.DeepEqual(got, wantMD5) {
+		t.Errorf("schedule MD5s = %v, want %v", got, wantMD5)
+	}
+}
+
+func TestPruneSchedule(t *testing.T) {
+	originalLogger := logger
+	defer func() { logger = originalLogger }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var c cache
+	c.Init()
+
+	data := []byte(`[
+		{"stationID": "90447", "metadata": {"startDate": "2024-03-09", "md5": "old"}, "programs": [
+			{"programID": "EP000000010001", "airDateTime": "2024-03-09T20:00:00Z", "duration": 3600}]},
+		{"stationID": "90447", "metadata": {"startDate": "2024-03-10", "md5": "day1"}, "programs": [
+			{"programID": "EP000000010002", "airDateTime": "2024-03-10T20:00:00Z", "duration": 3600}]},
+		{"stationID": "12345", "metadata": {"startDate": "2024-03-10", "md5": "gone"}, "programs": [
+			{"programID": "EP000000030001", "airDateTime": "2024-03-10T20:00:00Z", "duration": 3600}]}]`)
+	c.AddSchedule(&data)
+
+	c.PruneSchedule([]string{"90447"}, []string{"2024-03-10", "2024-03-11"})
+
+	if got, want := scheduleIDs(&c, "90447"), []string{"EP000000010002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("schedule = %v, want %v", got, want)
+	}
+	if got, want := c.ScheduleMD5["90447"], map[string]string{"2024-03-10": "day1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("schedule MD5s = %v, want %v", got, want)
+	}
+	if _, ok := c.Schedule["12345"]; ok {
+		t.Errorf("schedule of the removed station 12345 kept")
+	}
+	if _, ok := c.ScheduleMD5["12345"]; ok {
+		t.Errorf("schedule MD5s of the removed station 12345 kept")
+	}
+}
+
+func TestRequiredProgramIDs(t *testing.T) {
+	originalLogger := logger
+	defer func() { logger = originalLogger }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var c cache
+	c.Init()
+
+	data := []byte(`[{"stationID": "90447", "metadata": {"startDate": "2024-03-10", "md5": "day1"}, "programs": [
+		{"programID": "EP000000010001", "airDateTime": "2024-03-10T18:00:00Z", "duration": 3600, "md5": "same"},
+		{"programID": "EP000000010002", "airDateTime": "2024-03-10T19:00:00Z", "duration": 3600, "md5": "updated"},
+		{"programID": "EP000000010003", "airDateTime": "2024-03-10T20:00:00Z", "duration": 3600, "md5": "new"},
+		{"programID": "EP000000010001", "airDateTime": "2024-03-10T21:00:00Z", "duration": 3600, "md5": "same"}]}]`)
+	c.AddSchedule(&data)
+
+	c.Program["EP000000010001"] = EPGoCache{Md5: "same"}
+	c.Program["EP000000010002"] = EPGoCache{Md5: "outdated"}
+
+	required := c.GetRequiredProgramIDs()
+	sort.Strings(required)
+	if want := []string{"EP000000010002", "EP000000010003"}; !reflect.DeepEqual(required, want) {
+		t.Errorf("GetRequiredProgramIDs() = %v, want %v", required, want)
+	}
+
+	if got, want := c.GetChangedProgramIDs(required), []string{"EP000000010002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChangedProgramIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestCacheOpenCorrupt(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	Cache.Lock()
+	channels, programs, metadata, schedule, scheduleMD5 := Cache.Channel, Cache.Program, Cache.Metadata, Cache.Schedule, Cache.ScheduleMD5
+	Cache.Channel, Cache.Program, Cache.Metadata, Cache.Schedule, Cache.ScheduleMD5 = nil, nil, nil, nil, nil
+	Cache.Unlock()
+	defer func() {
+		Cache.Lock()
+		Cache.Channel, Cache.Program, Cache.Metadata, Cache.Schedule, Cache.ScheduleMD5 = channels, programs, metadata, schedule, scheduleMD5
+		Cache.Unlock()
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Files.Cache = filepath.Join(t.TempDir(), "config_cache.json")
+	truncated := []byte(`{"Program": {"EP000000010001": {"md5": "ab`)
+	if err := os.WriteFile(Config.Files.Cache, truncated, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if err := Cache.Open(); err != nil {
+		t.Fatalf("Open() error = %v, want the corrupt cache to be rebuilt", err)
+	}
+
+	moved, err := os.ReadFile(Config.Files.Cache + ".corrupt")
+	if err != nil || string(moved) != string(truncated) {
+		t.Fatalf("corrupt cache not moved aside: %q, %v", moved, err)
+	}
+
+	data, err := os.ReadFile(Config.Files.Cache)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("rebuilt cache file is not valid JSON: %s", data)
+	}
+
+	Cache.RLock()
+	defer Cache.RUnlock()
+	if Cache.Program == nil || Cache.Schedule == nil || len(Cache.Program) != 0 {
+		t.Fatalf("cache not reinitialized: %d programs", len(Cache.Program))
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// channelRule matches lineup stations. Every field that is set has to match;
+// Callsign, Name and Affiliate are case-insensitive regular expressions.
+type channelRule struct {
+	Lineup    string `yaml:"Lineup,omitempty"`
+	Callsign  string `yaml:"Callsign,omitempty"`
+	Name      string `yaml:"Name,omitempty"`
+	Language  string `yaml:"Language,omitempty"`  // broadcast language, e.g. en
+	Affiliate string `yaml:"Affiliate,omitempty"` // e.g. "ABC|CBS|NBC"
+	HD        *bool  `yaml:"HD,omitempty"`        // true = HD stations only, false = SD stations only
+
+	// Compiled Callsign, Name and Affiliate (see compileChannelFilter)
+	callsign, name, affiliate *regexp.Regexp
+}
+
+// filterStation is the part of a lineup station the channel rules look at.
+type filterStation struct {
+	Lineup    string
+	StationID string
+	Name      string
+	Callsign  string
+	Affiliate string
+	Languages []string
+}
+
+// isHD reports whether the callsign or name marks an HD feed ("KCBSHD", "Das Erste HD").
+func (s filterStation) isHD() bool {
+	for _, v := range []string{s.Callsign, s.Name} {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if strings.HasSuffix(v, "HD") || strings.Contains(v, " HD ") {
+			return true
+		}
+	}
+	return false
+}
+
+// compile compiles the regular expressions of the rule.
+func (r *channelRule) compile() (err error) {
+
+	for _, f := range []struct {
+		field, expr string
+		re          **regexp.Regexp
+	}{
+		{"Callsign", r.Callsign, &r.callsign},
+		{"Name", r.Name, &r.name},
+		{"Affiliate", r.Affiliate, &r.affiliate},
+	} {
+		*f.re = nil
+		if len(f.expr) == 0 {
+			continue
+		}
+		if *f.re, err = regexp.Compile("(?i)" + f.expr); err != nil {
+			return fmt.Errorf("%s: %w", f.field, err)
+		}
+	}
+
+	return
+}
+
+// compileChannelFilter compiles the Include and Exclude rules. Config.Open
+// calls it, so an invalid expression is reported when the config is loaded.
+func (c *config) compileChannelFilter() error {
+
+	for _, list := range []struct {
+		name  string
+		rules []channelRule
+	}{
+		{"Include", c.ChannelFilter.Include},
+		{"Exclude", c.ChannelFilter.Exclude},
+	} {
+		for i := range list.rules {
+			if err := list.rules[i].compile(); err != nil {
+				return fmt.Errorf("invalid Channel Filter %s rule %d: %w", list.name, i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchRegexp reports whether a compiled rule expression matches value.
+// Expressions that were not compiled never match.
+func matchRegexp(expr string, re *regexp.Regexp, value string) bool {
+	return len(expr) == 0 || (re != nil && re.MatchString(value))
+}
+
+func (r channelRule) match(s filterStation) bool {
+
+	if len(r.Lineup) != 0 && !strings.EqualFold(strings.TrimSpace(r.Lineup), s.Lineup) {
+		return false
+	}
+
+	if !matchRegexp(r.Callsign, r.callsign, s.Callsign) {
+		return false
+	}
+
+	if !matchRegexp(r.Name, r.name, s.Name) {
+		return false
+	}
+
+	if !matchRegexp(r.Affiliate, r.affiliate, s.Affiliate) {
+		return false
+	}
+
+	if len(r.Language) != 0 {
+		var found bool
+		for _, l := range s.Languages {
+			if strings.EqualFold(strings.TrimSpace(r.Language), l) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.HD != nil && *r.HD != s.isHD() {
+		return false
+	}
+
+	return true
+}
+
+// selected reports whether a lineup station is chosen by the Channel Filter:
+// it matches one of the Include rules and none of the Exclude rules.
+func (c *config) selected(s filterStation) bool {
+
+	var included bool
+	for _, r := range c.ChannelFilter.Include {
+		if r.match(s) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, r := range c.ChannelFilter.Exclude {
+		if r.match(s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ResetChannelFilter forgets the stations chosen by the Channel Filter on the last refresh.
+func (c *config) ResetChannelFilter() {
+	c.FilterStations = nil
+}
+
+// AddFilteredStations evaluates the Channel Filter against the stations of a
+// lineup (SD lineups/{lineup} response) and remembers the matching stations
+// that are not already in the Station list.
+func (c *config) AddFilteredStations(data *[]byte, lineup string) {
+
+	if len(c.ChannelFilter.Include) == 0 {
+		return
+	}
+
+	var sdData SDStation
+
+	err := json.Unmarshal(*data, &sdData)
+	if err != nil {
+		logger.Error("unable to unmarshal the JSON", "error", err)
+		return
+	}
+
+	var existing = make(map[string]bool)
+	for _, channel := range c.Station {
+		existing[channel.ID] = true
+	}
+	for _, channel := range c.FilterStations {
+		existing[channel.ID] = true
+	}
+
+	var added int
+	for _, sd := range sdData.Stations {
+
+		if existing[sd.StationID] {
+			continue
+		}
+
+		var s = filterStation{
+			Lineup:    lineup,
+			StationID: sd.StationID,
+			Name:      sd.Name,
+			Callsign:  sd.Callsign,
+			Affiliate: sd.Affiliate,
+			Languages: sd.BroadcastLanguage,
+		}
+
+		if c.selected(s) {
+			c.FilterStations = append(c.FilterStations, channel{Name: sd.Name, ID: sd.StationID, Lineup: lineup})
+			existing[sd.StationID] = true
+			added++
+		}
+
+	}
+
+	logger.Info("Channel Filter", "lineup", lineup, "stations", len(sdData.Stations), "added", added)
+}
+
+// Stations returns the Station list followed by the stations chosen by the Channel Filter.
+func (c *config) Stations() []channel {
+	if len(c.FilterStations) == 0 {
+		return c.Station
+	}
+
+	stations := make([]channel, 0, len(c.Station)+len(c.FilterStations))
+	stations = append(stations, c.Station...)
+	return append(stations, c.FilterStations...)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChannelFilterSelected(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	hd, sd := true, false
+
+	Config.ChannelFilter.Include = []channelRule{
+		{Lineup: "USA-NY12345-X", Affiliate: "^(ABC|CBS|NBC)", HD: &hd},
+		{Name: "^bbc", Language: "en"},
+	}
+	Config.ChannelFilter.Exclude = []channelRule{
+		{Callsign: "^WXYZ"},
+		{Name: "bbc alba", HD: &sd},
+	}
+	if err := Config.compileChannelFilter(); err != nil {
+		t.Fatalf("compileChannelFilter() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		station filterStation
+		want    bool
+	}{
+		{
+			name:    "network affiliate in HD",
+			station: filterStation{Lineup: "USA-NY12345-X", Callsign: "WCBSHD", Affiliate: "CBS Affiliate"},
+			want:    true,
+		},
+		{
+			name:    "network affiliate in SD",
+			station: filterStation{Lineup: "USA-NY12345-X", Callsign: "WCBS", Affiliate: "CBS Affiliate"},
+			want:    false,
+		},
+		{
+			name:    "other lineup",
+			station: filterStation{Lineup: "USA-NJ00000-X", Callsign: "WCBSHD", Affiliate: "CBS Affiliate"},
+			want:    false,
+		},
+		{
+			name:    "excluded callsign",
+			station: filterStation{Lineup: "USA-NY12345-X", Callsign: "WXYZHD", Affiliate: "ABC Affiliate"},
+			want:    false,
+		},
+		{
+			name:    "name and language",
+			station: filterStation{Name: "BBC One HD", Languages: []string{"en"}},
+			want:    true,
+		},
+		{
+			name:    "name with other language",
+			station: filterStation{Name: "BBC One", Languages: []string{"gd"}},
+			want:    false,
+		},
+		{
+			name:    "excluded SD feed",
+			station: filterStation{Name: "BBC Alba", Languages: []string{"en"}},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Config.selected(tt.station); got != tt.want {
+				t.Fatalf("selected(%+v) = %v, want %v", tt.station, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelFilterInvalidExpression(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	data := []byte(`Channel Filter:
+  Include:
+    - Lineup: USA-NY12345-X
+    - Callsign: "[invalid"
+`)
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	var c = config{File: filepath.Join(dir, "config")}
+	err := c.Open()
+	if err == nil || !strings.Contains(err.Error(), "Include rule 2: Callsign") {
+		t.Fatalf("Open() error = %v, want the invalid Include rule 2", err)
+	}
+
+	// Rules that were never compiled do not match
+	rule := channelRule{Callsign: "^WCBS"}
+	if rule.match(filterStation{Callsign: "WCBSHD"}) {
+		t.Fatalf("uncompiled rule matched")
+	}
+	if err := rule.compile(); err != nil || !rule.match(filterStation{Callsign: "WCBSHD"}) {
+		t.Fatalf("compiled rule: error = %v, no match", err)
+	}
+}
+
+func TestAddFilteredStations(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Station = []channel{{Name: "Das Erste HD", ID: "90447", Lineup: "DEU-1000097-DEFAULT"}}
+	Config.ChannelFilter.Include = []channelRule{{Language: "de"}}
+	Config.ChannelFilter.Exclude = nil
+	Config.ResetChannelFilter()
+
+	data := []byte(`{"stations":[
+		{"stationID":"90447","name":"Das Erste HD","callsign":"ARDGRHD","broadcastLanguage":["de"]},
+		{"stationID":"90457","name":"one HD","callsign":"ONEHD","broadcastLanguage":["de"]},
+		{"stationID":"66603","name":"Eurosport 1","callsign":"EUROSGR","broadcastLanguage":["en"]}
+	]}`)
+
+	Config.AddFilteredStations(&data, "DEU-1000097-DEFAULT")
+	Config.AddFilteredStations(&data, "DEU-1000097-DEFAULT")
+
+	got := Config.GetChannelList("")
+	want := []string{"90447", "90457"}
+	if len(got) != len(want) {
+		t.Fatalf("GetChannelList() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("GetChannelList()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if country := Config.GetLineupCountry("90457"); country != "DEU" {
+		t.Fatalf("GetLineupCountry() = %q, want %q", country, "DEU")
+	}
+}
@@ -65,14 +65,19 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 	}
 
 	entry.headline()
-	var channelNames []string
 	var existing map[string]bool
 
-	for _, station := range sd.Resp.Lineup.Stations {
-		channelNames = append(channelNames, station.Name)
-	}
+	// Sort by channel number from the lineup map, then by name
+	var numbers = lineupChannelNumbers(sd.Resp.Lineup.Map)
+	var stations = make([]Station, len(sd.Resp.Lineup.Stations))
+	copy(stations, sd.Resp.Lineup.Stations)
 
-	sort.Strings(channelNames)
+	sort.SliceStable(stations, func(i, j int) bool {
+		if c := compareChannelNumbers(numbers[stations[i].StationID], numbers[stations[j].StationID]); c != 0 {
+			return c < 0
+		}
+		return stations[i].Name < stations[j].Name
+	})
 
 	Config.GetChannels()
 
@@ -83,24 +88,20 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 
 	// Prepare items for promptui
 	var promptItems []map[string]interface{}
-	for _, cName := range channelNames {
-		for _, station := range sd.Resp.Lineup.Stations {
-			if cName == station.Name {
-				status := "-"
-				if existing[station.StationID] {
-					status = "+"
-				}
-				promptItems = append(promptItems, map[string]interface{}{
-					"Name":     station.Name,
-					"ID":       station.StationID,
-					"Display":  fmt.Sprintf("[%s] %s [%s] %v", status, station.Name, station.StationID, station.BroadcastLanguage), //Pre-calculate Display
-					"Status":   status,
-					"Lineup":   entry.Lineup,
-					"Language": station.BroadcastLanguage, // Store language for searching
-				})
-				break
-			}
+	for _, station := range stations {
+		status := "-"
+		if existing[station.StationID] {
+			status = "+"
 		}
+		promptItems = append(promptItems, map[string]interface{}{
+			"Name":     station.Name,
+			"ID":       station.StationID,
+			"Number":   numbers[station.StationID],
+			"Display":  channelDisplay(status, numbers[station.StationID], station.Name, station.StationID, station.BroadcastLanguage), //Pre-calculate Display
+			"Status":   status,
+			"Lineup":   entry.Lineup,
+			"Language": station.BroadcastLanguage, // Store language for searching
+		})
 	}
 
 	templates := &promptui.SelectTemplates{
@@ -111,6 +112,7 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 		Details: `
 --------- Channel Details ----------
 {{ "Name:" | faint }}	{{ .Name }}
+{{ "Number:" | faint }}	{{ .Number }}
 {{ "ID:" | faint }}	{{ .ID }}
 `,
 	}
@@ -123,12 +125,13 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 		CursorPos: selection,
 	}
 
-	// custom searcher function based on channel name, station id and language
+	// custom searcher function based on channel name and channel number
 	prompt.Searcher = func(input string, index int) bool {
 		item := promptItems[index]
 		name := item["Name"].(string)
+		number := item["Number"].(string)
 
-		return caseInsensitiveContains(name, input)
+		return caseInsensitiveContains(name, input) || (len(number) != 0 && strings.HasPrefix(number, strings.TrimSpace(input)))
 	}
 
 	for {
@@ -154,7 +157,7 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 		}
 
 		// Update the Display field *after* toggling the status
-		item["Display"] = fmt.Sprintf("[%s] %s [%s] %v", item["Status"], item["Name"], item["ID"], item["Language"])
+		item["Display"] = channelDisplay(item["Status"].(string), item["Number"].(string), item["Name"].(string), item["ID"].(string), item["Language"].([]string))
 
 		// Update promptItems to reflect the change
 		promptItems[index] = item
@@ -175,6 +178,42 @@ func (e *Entry) manageChannels(sd *SD) (err error) {
 	return
 }
 
+// channelDisplay formats a channel line for the Manage Channels prompt.
+func channelDisplay(status, number, name, id string, language []string) string {
+	if len(number) != 0 {
+		return fmt.Sprintf("[%s] %s %s [%s] %v", status, number, name, id, language)
+	}
+	return fmt.Sprintf("[%s] %s [%s] %v", status, name, id, language)
+}
+
+// Number returns the logical channel number of a lineup map entry: the
+// channel, the DVB logical channel number or the ATSC major.minor.
+func (m SDChannelMap) Number() string {
+	switch {
+	case strings.TrimSpace(m.Channel) != "":
+		return strings.TrimSpace(m.Channel)
+	case strings.TrimSpace(m.LogicalChannelNumber) != "":
+		return strings.TrimSpace(m.LogicalChannelNumber)
+	case m.AtscMajor != 0:
+		return fmt.Sprintf("%d.%d", m.AtscMajor, m.AtscMinor)
+	}
+	return ""
+}
+
+// lineupChannelNumbers maps stationID -> channel number (first entry wins).
+func lineupChannelNumbers(maps []SDChannelMap) map[string]string {
+	numbers := make(map[string]string, len(maps))
+	for _, m := range maps {
+		if _, ok := numbers[m.StationID]; ok {
+			continue
+		}
+		if n := m.Number(); n != "" {
+			numbers[m.StationID] = n
+		}
+	}
+	return numbers
+}
+
 // Helper function for case-insensitive contains
 func caseInsensitiveContains(s, substr string) bool {
 	s = strings.ToLower(s)
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Non-interactive counterparts of the Configure menu:
+//
+//	epgo -config config.yaml [-output table|json] lineup list|add|remove [lineup]
+//	epgo -config config.yaml [-output table|json] countries
+//	epgo -config config.yaml [-output table|json] headends <country> <postalcode>
+//	epgo -config config.yaml [-output table|json] satellites
+//	epgo -config config.yaml [-output table|json] transmitters <country>
+//	epgo -config config.yaml [-output table|json] channels list [lineup]
+//	epgo -config config.yaml [-output table|json] channels add|remove <stationID...>
+
+type cliLineup struct {
+	Lineup   string `json:"lineup"`
+	Name     string `json:"name"`
+	Modified string `json:"modified,omitempty"`
+}
+
+type cliHeadend struct {
+	Headend   string `json:"headend"`
+	Transport string `json:"transport"`
+	Location  string `json:"location"`
+	Lineup    string `json:"lineup"`
+	Name      string `json:"name"`
+}
+
+type cliChannel struct {
+	Number     string   `json:"number,omitempty"`
+	StationID  string   `json:"stationID"`
+	Name       string   `json:"name"`
+	Callsign   string   `json:"callsign,omitempty"`
+	Language   []string `json:"language,omitempty"`
+	Lineup     string   `json:"lineup"`
+	Configured bool     `json:"configured"`
+}
+
+type cliResult struct {
+	Command string   `json:"command"`
+	Lineup  string   `json:"lineup,omitempty"`
+	IDs     []string `json:"stationIDs,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// printCommandUsage lists the subcommands below the flags of -h.
+func printCommandUsage(w io.Writer) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands (after the flags, require -config):")
+	fmt.Fprintln(w, "  lineup list                        List the lineups of the account")
+	fmt.Fprintln(w, "  lineup add <lineup>                Add a lineup to the account")
+	fmt.Fprintln(w, "  lineup remove <lineup>             Remove a lineup from the account")
+	fmt.Fprintln(w, "  countries                          List the countries by region")
+	fmt.Fprintln(w, "  headends <country> <postalcode>    List headends and lineups, e.g. headends DEU 10115")
+	fmt.Fprintln(w, "  satellites                         List the satellite (DVB-S) lineups")
+	fmt.Fprintln(w, "  transmitters <country>             List the antenna transmitter lineups, e.g. transmitters GBR")
+	fmt.Fprintln(w, "  channels list [lineup]             List the stations of a lineup, or the configured stations")
+	fmt.Fprintln(w, "  channels add <stationID...>        Add stations from the account lineups to the config")
+	fmt.Fprintln(w, "  channels remove <stationID...>     Remove stations from the config")
+}
+
+// RunCommand runs a non-interactive lineup, headends or channels command.
+func RunCommand(filename string, args []string, format string) (err error) {
+
+	var sd SD
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown output format %q (table or json)", format)
+	}
+
+	if len(filename) == 0 {
+		return fmt.Errorf("the %s command requires -config", args[0])
+	}
+
+	if _, err = os.Stat(filename); err != nil {
+		return fmt.Errorf("configuration file not found, create it with -configure: %w", err)
+	}
+
+	Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	if err = Config.Open(); err != nil {
+		return
+	}
+
+	// Schedules Direct login, only for the commands that need it
+	connect := func() (err error) {
+		if err = sd.Init(); err != nil {
+			return
+		}
+		if err = applyCachedToken(&sd); err != nil {
+			return
+		}
+		return sd.Status()
+	}
+
+	sub := ""
+	if len(args) > 1 {
+		sub = args[1]
+	}
+
+	switch args[0] {
+
+	case "lineup", "lineups":
+		switch sub {
+		case "list":
+			if err = connect(); err != nil {
+				return
+			}
+			return cliLineupList(&sd, format)
+		case "add", "remove":
+			if len(args) != 3 {
+				return fmt.Errorf("usage: lineup %s <lineup>", sub)
+			}
+			if err = connect(); err != nil {
+				return
+			}
+			return cliLineupChange(&sd, format, sub, args[2])
+		}
+		return fmt.Errorf("usage: lineup list|add|remove [lineup]")
+
+	case "headends":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: headends <country> <postalcode>")
+		}
+		if err = connect(); err != nil {
+			return
+		}
+		return cliHeadends(&sd, format, args[1], args[2])
+
+	case "countries":
+		if err = connect(); err != nil {
+			return
+		}
+		return cliCountries(&sd, format)
+
+	case "satellites":
+		if err = connect(); err != nil {
+			return
+		}
+		lineups, lerr := satelliteLineups(&sd)
+		if lerr != nil {
+			return lerr
+		}
+		return cliDiscoveredLineups(format, lineups)
+
+	case "transmitters":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: transmitters <country>")
+		}
+		if err = connect(); err != nil {
+			return
+		}
+		sd.Req.Parameter = fmt.Sprintf("/%s", strings.ToUpper(args[1]))
+		lineups, lerr := transmitterLineups(&sd)
+		if lerr != nil {
+			return lerr
+		}
+		return cliDiscoveredLineups(format, lineups)
+
+	case "channels", "channel":
+		switch sub {
+		case "list":
+			if len(args) > 3 {
+				return fmt.Errorf("usage: channels list [lineup]")
+			}
+			var lineup string
+			if len(args) == 3 {
+				lineup = args[2]
+				if err = connect(); err != nil {
+					return
+				}
+			}
+			return cliChannelList(&sd, format, lineup)
+		case "add", "remove":
+			if len(args) < 3 {
+				return fmt.Errorf("usage: channels %s <stationID...>", sub)
+			}
+			if sub == "add" {
+				if err = connect(); err != nil {
+					return
+				}
+			}
+			return cliChannelChange(&sd, format, sub, args[2:])
+		}
+		return fmt.Errorf("usage: channels list|add|remove")
+
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func cliLineupList(sd *SD, format string) error {
+
+	var lineups = make([]cliLineup, 0, len(sd.Resp.Status.Lineups))
+	var rows [][]string
+
+	for _, l := range sd.Resp.Status.Lineups {
+		lineups = append(lineups, cliLineup{Lineup: l.Lineup, Name: l.Name, Modified: l.Modified})
+		rows = append(rows, []string{l.Lineup, l.Name, l.Modified})
+	}
+
+	return printCLI(format, lineups, []string{"LINEUP", "NAME", "MODIFIED"}, rows)
+}
+
+func cliLineupChange(sd *SD, format, action, lineup string) (err error) {
+
+	sd.Req.Parameter = fmt.Sprintf("/%s", lineup)
+	sd.Req.Type = "PUT"
+	if action == "remove" {
+		sd.Req.Type = "DELETE"
+	}
+
+	err = sd.Lineups()
+	if err != nil {
+		if len(sd.Resp.Lineup.Message) != 0 {
+			err = fmt.Errorf("%s: %w", sd.Resp.Lineup.Message, err)
+		}
+		return
+	}
+
+	result := cliResult{
+		Command: "lineup " + action,
+		Lineup:  lineup,
+		Message: sd.Resp.Lineup.Message,
+	}
+
+	return printCLI(format, result, []string{"LINEUP", "RESULT", "CHANGES REMAINING"},
+		[][]string{{lineup, sd.Resp.Lineup.Response, fmt.Sprint(sd.Resp.Lineup.ChangesRemaining)}})
+}
+
+func cliHeadends(sd *SD, format, country, postalcode string) (err error) {
+
+	sd.Req.Parameter = fmt.Sprintf("?country=%s&postalcode=%s", url.QueryEscape(country), url.QueryEscape(postalcode))
+
+	err = sd.Headends()
+	if err != nil {
+		return
+	}
+
+	var headends = make([]cliHeadend, 0)
+	var rows [][]string
+
+	for _, h := range sd.Resp.Headend {
+		for _, l := range h.Lineups {
+			headends = append(headends, cliHeadend{
+				Headend:   h.Headend,
+				Transport: h.Transport,
+				Location:  h.Location,
+				Lineup:    l.Lineup,
+				Name:      l.Name,
+			})
+			rows = append(rows, []string{l.Lineup, l.Name, h.Transport, h.Location, h.Headend})
+		}
+	}
+
+	return printCLI(format, headends, []string{"LINEUP", "NAME", "TRANSPORT", "LOCATION", "HEADEND"}, rows)
+}
+
+func cliCountries(sd *SD, format string) (err error) {
+
+	err = sd.Countries()
+	if err != nil {
+		return
+	}
+
+	type cliCountry struct {
+		Region string `json:"region"`
+		SDCountry
+	}
+
+	var countries = make([]cliCountry, 0)
+	var rows [][]string
+
+	for _, region := range countryRegions(sd.Resp.Countries) {
+		for _, c := range sd.Resp.Countries[region] {
+			countries = append(countries, cliCountry{Region: region, SDCountry: c})
+			rows = append(rows, []string{c.ShortName, c.FullName, region, c.PostalCodeExample})
+		}
+	}
+
+	return printCLI(format, countries, []string{"COUNTRY", "NAME", "REGION", "POSTAL CODE EXAMPLE"}, rows)
+}
+
+// cliDiscoveredLineups prints satellite or transmitter lineups.
+func cliDiscoveredLineups(format string, entries []Entry) error {
+
+	var lineups = make([]cliLineup, 0, len(entries))
+	var rows [][]string
+
+	for _, e := range entries {
+		lineups = append(lineups, cliLineup{Lineup: e.Lineup, Name: e.Value})
+		rows = append(rows, []string{e.Lineup, e.Value, e.Transport})
+	}
+
+	return printCLI(format, lineups, []string{"LINEUP", "NAME", "TRANSPORT"}, rows)
+}
+
+// lineupChannels downloads the stations of a lineup, sorted by channel number and name.
+func lineupChannels(sd *SD, lineup string) (channels []cliChannel, err error) {
+
+	sd.Req.Parameter = fmt.Sprintf("/%s", lineup)
+	sd.Req.Type = "GET"
+
+	err = sd.Lineups()
+	if err != nil {
+		return
+	}
+
+	var cfg = currentConfig()
+	var configured = make(map[string]bool)
+	for _, id := range cfg.GetChannelList("") {
+		configured[id] = true
+	}
+
+	var numbers = lineupChannelNumbers(sd.Resp.Lineup.Map)
+
+	for _, s := range sd.Resp.Lineup.Stations {
+		channels = append(channels, cliChannel{
+			Number:     numbers[s.StationID],
+			StationID:  s.StationID,
+			Name:       s.Name,
+			Callsign:   s.Callsign,
+			Language:   s.BroadcastLanguage,
+			Lineup:     lineup,
+			Configured: configured[s.StationID],
+		})
+	}
+
+	sort.SliceStable(channels, func(i, j int) bool {
+		if c := compareChannelNumbers(channels[i].Number, channels[j].Number); c != 0 {
+			return c < 0
+		}
+		return channels[i].Name < channels[j].Name
+	})
+
+	return
+}
+
+func cliChannelList(sd *SD, format, lineup string) (err error) {
+
+	var channels []cliChannel
+
+	if len(lineup) != 0 {
+		channels, err = lineupChannels(sd, lineup)
+		if err != nil {
+			return
+		}
+	} else {
+		for _, st := range Config.Station {
+			channels = append(channels, cliChannel{
+				Number:     st.Number,
+				StationID:  st.ID,
+				Name:       st.Name,
+				Lineup:     st.Lineup,
+				Configured: true,
+			})
+		}
+	}
+
+	var rows [][]string
+	for _, ch := range channels {
+		status := "-"
+		if ch.Configured {
+			status = "+"
+		}
+		rows = append(rows, []string{status, ch.Number, ch.StationID, ch.Name, ch.Callsign, strings.Join(ch.Language, ","), ch.Lineup})
+	}
+
+	if channels == nil {
+		channels = []cliChannel{}
+	}
+
+	return printCLI(format, channels, []string{"", "NUMBER", "ID", "NAME", "CALLSIGN", "LANGUAGE", "LINEUP"}, rows)
+}
+
+func cliChannelChange(sd *SD, format, action string, ids []string) (err error) {
+
+	var rows [][]string
+	var done []string
+
+	switch action {
+
+	case "add":
+		var pending = make(map[string]bool)
+		for _, id := range ids {
+			pending[normalizeStationID(id)] = true
+		}
+
+		for _, l := range sd.Resp.Status.Lineups {
+
+			if len(pending) == 0 {
+				break
+			}
+
+			channels, lerr := lineupChannels(sd, l.Lineup)
+			if lerr != nil {
+				return lerr
+			}
+
+			for _, ch := range channels {
+				if !pending[ch.StationID] {
+					continue
+				}
+				Config.AddChannel(&channel{Name: ch.Name, ID: ch.StationID, Lineup: ch.Lineup})
+				delete(pending, ch.StationID)
+				done = append(done, ch.StationID)
+				rows = append(rows, []string{"added", ch.StationID, ch.Name, ch.Lineup})
+			}
+
+		}
+
+		if len(pending) != 0 {
+			var missing []string
+			for id := range pending {
+				missing = append(missing, id)
+			}
+			sort.Strings(missing)
+			return fmt.Errorf("station not found in the account lineups: %s", strings.Join(missing, ", "))
+		}
+
+	case "remove":
+		var configured = make(map[string]channel)
+		for _, st := range Config.Station {
+			configured[st.ID] = st
+		}
+
+		for _, id := range ids {
+			id = normalizeStationID(id)
+			st, ok := configured[id]
+			if !ok {
+				return fmt.Errorf("station %s is not in the config", id)
+			}
+			Config.RemoveChannel(&st)
+			done = append(done, id)
+			rows = append(rows, []string{"removed", st.ID, st.Name, st.Lineup})
+		}
+
+	}
+
+	if err = Config.Save(); err != nil {
+		return
+	}
+
+	return printCLI(format, cliResult{Command: "channels " + action, IDs: done}, []string{"RESULT", "ID", "NAME", "LINEUP"}, rows)
+}
+
+// printCLI writes v as JSON, or header and rows as an aligned table, to stdout.
+func printCLI(format string, v interface{}, header []string, rows [][]string) error {
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
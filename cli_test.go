@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCommandChannelsRemove(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	data := []byte(`Files:
+  Cache: ` + filepath.Join(dir, "config_cache.json") + `
+  XMLTV: ` + filepath.Join(dir, "config.xml") + `
+Options:
+  Insert credits tag into XML file: false
+  Rating:
+    Insert rating tag into XML file: false
+Station:
+  - Name: Das Erste HD
+    ID: "90447"
+    Lineup: DEU-1000097-DEFAULT
+  - Name: one HD
+    ID: "90457"
+    Lineup: DEU-1000097-DEFAULT
+`)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	Config = config{}
+	if err := RunCommand(filename, []string{"channels", "remove", "90457.schedulesdirect.org"}, "json"); err != nil {
+		t.Fatalf("RunCommand(channels remove) error = %v", err)
+	}
+
+	Config = config{}
+	if err := RunCommand(filename, []string{"channels", "list"}, "table"); err != nil {
+		t.Fatalf("RunCommand(channels list) error = %v", err)
+	}
+	if ids := Config.GetChannelList(""); len(ids) != 1 || ids[0] != "90447" {
+		t.Fatalf("stations after remove = %v, want [90447]", ids)
+	}
+
+	if err := RunCommand(filename, []string{"channels", "remove", "12345"}, "table"); err == nil {
+		t.Fatalf("RunCommand(channels remove unknown) error = nil, want error")
+	}
+	if err := RunCommand(filename, []string{"channels", "list"}, "yaml"); err == nil {
+		t.Fatalf("RunCommand() with unknown output format error = nil, want error")
+	}
+	if err := RunCommand(filename, []string{"programs"}, "table"); err == nil {
+		t.Fatalf("RunCommand(programs) error = nil, want error")
+	}
+}
+
+func TestCLIHeadendsEscapesQuery(t *testing.T) {
+	originalLogger := logger
+	defer func() { logger = originalLogger }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var country, postalcode string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		country, postalcode = r.URL.Query().Get("country"), r.URL.Query().Get("postalcode")
+		w.Write([]byte(`[{"headend": "GBR-1000014", "transport": "Antenna", "location": "London", "lineups": [{"lineup": "GBR-1000014-DEFAULT", "name": "Crystal Palace"}]}]`))
+	}))
+	defer srv.Close()
+
+	var sd SD
+	if err := sd.Init(); err != nil {
+		t.Fatalf("sd.Init() error = %v", err)
+	}
+	sd.BaseURL = srv.URL + "/20141201/"
+
+	if err := cliHeadends(&sd, "json", "GBR", "SW1A 1AA&x=1"); err != nil {
+		t.Fatalf("cliHeadends() error = %v", err)
+	}
+
+	if country != "GBR" || postalcode != "SW1A 1AA&x=1" {
+		t.Errorf("query country = %q, postalcode = %q", country, postalcode)
+	}
+}
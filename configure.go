@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
+
+	"epgo/internal/atomicfile"
 )
 
 // Configure : Configure config file
@@ -142,6 +147,18 @@ func (c *config) Open() (err error) {
 		return
 	}
 
+	// Invalid Channel Filter expressions fail here instead of on every refresh
+	err = c.compileChannelFilter()
+	if err != nil {
+		return
+	}
+
+	// Likewise an unknown Output Timezone
+	err = c.loadOutputTimezone()
+	if err != nil {
+		return
+	}
+
 	/*
 	   New config options
 	*/
@@ -152,9 +169,9 @@ func (c *config) Open() (err error) {
 		rmCacheFile = true
 		newOptions = true
 
-		Config.Options.Credits = true
+		c.Options.Credits = true
 
-		logger.Info("update config file", "filename", Config.File+".yaml")
+		logger.Info("update config file", "filename", c.File+".yaml")
 	}
 
 	// Rating tag
@@ -162,62 +179,107 @@ func (c *config) Open() (err error) {
 
 		newOptions = true
 
-		Config.Options.Rating.Guidelines = true
-		Config.Options.Rating.Countries = []string{}
-		Config.Options.Rating.CountryCodeAsSystem = false
-		Config.Options.Rating.MaxEntries = 1
+		c.Options.Rating.Guidelines = true
+		c.Options.Rating.Countries = []string{}
+		c.Options.Rating.CountryCodeAsSystem = false
+		c.Options.Rating.MaxEntries = 1
 
-		logger.Info("update config file", "filename", Config.File+".yaml")
+		logger.Info("update config file", "filename", c.File+".yaml")
 
 	}
 
 	if !bytes.Contains(data, []byte("Live and New icons")) {
 		newOptions = true
-		Config.Options.LiveIcons = false
+		c.Options.LiveIcons = false
 	}
 
 	if !bytes.Contains(data, []byte("Skip EPG refresh")) {
 		newOptions = true
-		Config.Options.SkipRefreshHours = 0
+		c.Options.SkipRefreshHours = 0
+	}
+
+	if !bytes.Contains(data, []byte("Refresh Schedule")) {
+		newOptions = true
+		c.Options.RefreshSchedule = defaultRefreshSchedule
+	}
+
+	if !bytes.Contains(data, []byte("Sort Channels By")) {
+		newOptions = true
+		c.Options.SortChannelsBy = "config"
+	}
+
+	if !bytes.Contains(data, []byte("Schedule Day Offset")) {
+		newOptions = true
+		c.Options.ScheduleDayOffset = 0
+		c.Options.PastHours = 0
+	}
+
+	if !bytes.Contains(data, []byte("Output Timezone")) {
+		newOptions = true
+		c.Options.OutputTimezone = "UTC"
+	}
+
+	if !bytes.Contains(data, []byte("XMLTV gzip")) {
+		newOptions = true
+		c.Files.XMLTVGzip = false
+		c.Files.XMLTVXz = false
 	}
 
 	if !bytes.Contains(data, []byte("The MovieDB cache")) {
 		newOptions = true
-		Config.Files.TmdbCacheFile = ""
+		c.Files.TmdbCacheFile = ""
 	}
 
 	// SD errors
 	if !bytes.Contains(data, []byte("download errors")) {
 
 		newOptions = true
-		Config.Options.SDDownloadErrors = false
+		c.Options.SDDownloadErrors = false
 
-		logger.Info("update config file", "filename", Config.File+".yaml")
+		logger.Info("update config file", "filename", c.File+".yaml")
 	}
 
 	if !bytes.Contains(data, []byte("The MovieDB:")) {
 		newOptions = true
-		Config.Options.Images.Tmdb.Enable = false
-		Config.Options.Images.Tmdb.ApiKey = ""
+		c.Options.Images.Tmdb.Enable = false
+		c.Options.Images.Tmdb.ApiKey = ""
 	}
 
 	if !bytes.Contains(data, []byte("Images:")) {
 		newOptions = true
-		Config.Options.Images.Download = false
-		Config.Options.Images.Path = ""
-		Config.Options.Images.PreindexSDPosters = true
+		c.Options.Images.Download = false
+		c.Options.Images.Path = ""
+		c.Options.Images.PreindexSDPosters = true
 	}
 
 	if !bytes.Contains(data, []byte("Preindex SD Posters")) {
 		newOptions = true
-		Config.Options.Images.PreindexSDPosters = true
+		c.Options.Images.PreindexSDPosters = true
 	}
 
 	if !bytes.Contains(data, []byte("Server:")) {
 		newOptions = true
-		Config.Server.Enable = false
-		Config.Server.Address = "localhost"
-		Config.Server.Port = "80"
+		c.Server.Enable = false
+		c.Server.Address = "localhost"
+		c.Server.Port = "80"
+	}
+
+	if !bytes.Contains(data, []byte("Admin UI")) {
+		newOptions = true
+		c.Server.Admin.Enable = false
+		c.Server.Admin.Username = "admin"
+		c.Server.Admin.Password = ""
+	}
+
+	if !bytes.Contains(data, []byte("API Token")) {
+		newOptions = true
+		c.Server.APIToken = ""
+	}
+
+	if !bytes.Contains(data, []byte("Readiness:")) {
+		newOptions = true
+		c.Server.Readiness.MaxXMLTVAgeHours = defaultMaxXMLTVAgeHours
+		c.Server.Readiness.AccountExpiryDays = defaultAccountExpiryDays
 	}
 
 	if c.Server.Address == "" {
@@ -245,6 +307,120 @@ func (c *config) Open() (err error) {
 	return
 }
 
+// configMu guards the global Config while the HTTP server runs. Writers
+// (refresh, reload, the admin channel editor) hold refreshMu and take the write
+// lock only to swap in or change a value; handlers read a copy through
+// currentConfig.
+var configMu sync.RWMutex
+
+// currentConfig returns a copy of Config for code that runs outside refreshMu.
+// The copy shares slices with Config, writers replace them instead of
+// changing their elements.
+func currentConfig() config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return Config
+}
+
+// loadConfig reads the config file into a new value and publishes it as
+// Config, keeping the runtime state of the last refresh. It returns the
+// previous value. The caller holds refreshMu or runs before the server.
+func loadConfig(file string) (previous config, err error) {
+
+	var next = config{File: file}
+	if err = next.Open(); err != nil {
+		return
+	}
+
+	// Runtime state of the last refresh, not part of the file
+	next.ChannelIDs = Config.ChannelIDs
+	next.FilterStations = Config.FilterStations
+
+	configMu.Lock()
+	previous, Config = Config, next
+	configMu.Unlock()
+
+	return
+}
+
+// reloadConfig re-reads the config file like a refresh does, so keys removed
+// from the file fall back to their defaults. The caller holds refreshMu.
+func reloadConfig() (err error) {
+
+	previous, err := loadConfig(Config.File)
+	if err != nil {
+		return
+	}
+
+	changed := configChanges(previous, Config)
+
+	if len(changed) != 0 {
+		logger.Info("Config: reloaded", "filename", configFileName(), "changed", changed)
+	}
+
+	select {
+	case configReloaded <- struct{}{}:
+	default:
+	}
+
+	return
+}
+
+// configChanges returns the YAML paths (e.g. "Options.Refresh Schedule") whose
+// values differ. Values are left out, they may be passwords.
+func configChanges(previous, current config) (changed []string) {
+
+	var trees [2]map[string]interface{}
+	for i, c := range []config{previous, current} {
+		data, err := yaml.Marshal(&c)
+		if err != nil {
+			return
+		}
+		if err = yaml.Unmarshal(data, &trees[i]); err != nil {
+			return
+		}
+	}
+
+	diffConfigTree("", trees[0], trees[1], &changed)
+	sort.Strings(changed)
+
+	return
+}
+
+func diffConfigTree(prefix string, a, b map[string]interface{}, changed *[]string) {
+
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+
+	for k := range keys {
+		path := k
+		if len(prefix) != 0 {
+			path = prefix + "." + k
+		}
+
+		subA, okA := a[k].(map[string]interface{})
+		subB, okB := b[k].(map[string]interface{})
+		if okA && okB {
+			diffConfigTree(path, subA, subB, changed)
+			continue
+		}
+
+		if !reflect.DeepEqual(a[k], b[k]) {
+			*changed = append(*changed, path)
+		}
+	}
+}
+
+// configFileName returns the path of the loaded config file.
+func configFileName() string {
+	return fmt.Sprintf("%s.yaml", currentConfig().File)
+}
+
 func (c *config) Save() (err error) {
 
 	data, err := yaml.Marshal(&c)
@@ -252,7 +428,7 @@ func (c *config) Save() (err error) {
 		return err
 	}
 
-	err = os.WriteFile(fmt.Sprintf("%s.yaml", c.File), data, 0644)
+	err = atomicfile.WriteFile(fmt.Sprintf("%s.yaml", c.File), data, 0644)
 	if err != nil {
 		return
 	}
@@ -271,12 +447,23 @@ func (c *config) InitConfig() {
 	c.Server.Enable = false
 	c.Server.Address = "localhost"
 	c.Server.Port = "80"
+	c.Server.Admin.Enable = false
+	c.Server.Admin.Username = "admin"
+	c.Server.Admin.Password = ""
+	c.Server.APIToken = ""
+	c.Server.Readiness.MaxXMLTVAgeHours = defaultMaxXMLTVAgeHours
+	c.Server.Readiness.AccountExpiryDays = defaultAccountExpiryDays
 
 	// Options
 	c.Options.Schedule = 7
+	c.Options.ScheduleDayOffset = 0
+	c.Options.PastHours = 0
 	c.Options.SubtitleIntoDescription = false
 	c.Options.Credits = false
 	c.Options.SkipRefreshHours = 0
+	c.Options.RefreshSchedule = defaultRefreshSchedule
+	c.Options.SortChannelsBy = "config"
+	c.Options.OutputTimezone = "UTC"
 	Config.Options.Rating.Guidelines = true
 	Config.Options.Rating.Countries = []string{"USA", "CHE", "DE"}
 	Config.Options.Rating.CountryCodeAsSystem = false
@@ -293,7 +480,7 @@ func (c *config) InitConfig() {
 
 func (c *config) GetChannelList(lineup string) (list []string) {
 
-	for _, channel := range c.Station {
+	for _, channel := range c.Stations() {
 
 		switch len(lineup) {
 
@@ -314,7 +501,7 @@ func (c *config) GetChannelList(lineup string) (list []string) {
 
 func (c *config) GetLineupCountry(id string) (countryCode string) {
 
-	for _, channel := range c.Station {
+	for _, channel := range c.Stations() {
 
 		if id == channel.ID {
 			countryCode = strings.Split(channel.Lineup, "-")[0]
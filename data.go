@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -15,16 +14,15 @@ import (
 // Update : Update data from Schedules Direct and create the XMLTV file
 func (sd *SD) Update(filename string) (err error) {
 
-	Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
+	file := strings.TrimSuffix(filename, filepath.Ext(filename))
 
 	// reads the Config file
-	_, err = os.ReadFile(fmt.Sprintf("%s.yaml", Config.File))
+	_, err = os.ReadFile(fmt.Sprintf("%s.yaml", file))
 	if err != nil {
 		return
 	}
 
-	err = Config.Open()
-	if err != nil {
+	if _, err = loadConfig(file); err != nil {
 		return
 	}
 
@@ -75,8 +73,6 @@ func (sd *SD) Update(filename string) (err error) {
 		}
 	}
 
-	runtime.GC()
-
 	err = CreateXMLTV(filename)
 	if err != nil {
 		logger.Error("unable to create the XMLTV file", "error", err)
@@ -85,17 +81,6 @@ func (sd *SD) Update(filename string) (err error) {
 
 	Cache.CleanUp()
 
-	runtime.GC()
-
-	if Config.Server.Enable {
-		imagePath := Config.Options.Images.Path
-		if imagePath == "" {
-			imagePath = "images"
-		}
-		go StartServer(imagePath, Config.Server.Port)
-		select {}
-	}
-
 	return
 }
 
@@ -122,6 +107,11 @@ func (sd *SD) GetData() {
 		lineup = append(lineup, l.Lineup)
 	}
 
+	// Stations chosen by the Channel Filter are resolved against the current lineups
+	configMu.Lock()
+	Config.ResetChannelFilter()
+	configMu.Unlock()
+
 	for _, id := range lineup {
 
 		sd.Req.Parameter = fmt.Sprintf("/%s", id)
@@ -129,31 +119,42 @@ func (sd *SD) GetData() {
 
 		sd.Lineups()
 
+		configMu.Lock()
+		Config.AddFilteredStations(&sd.Resp.Body, id)
+		configMu.Unlock()
 		Cache.AddStations(&sd.Resp.Body, id)
 
 	}
 
 	// Schedule
-	logger.Info("Download Schedule", "days", Config.Options.Schedule)
-
 	var limit = 5000
 
-	var days = make([]string, 0)
 	var channels = make([]interface{}, 0)
+	var days = scheduleDays(scheduleWindow(time.Now()))
+
+	Cache.PruneSchedule(Config.GetChannelList(""), days)
 
-	for i := 0; i < Config.Options.Schedule; i++ {
-		var nextDay = time.Now().Add(time.Hour * time.Duration(24*i))
-		days = append(days, nextDay.Format("2006-01-02"))
+	var changed, unchanged = sd.changedScheduleDays(days)
+
+	var stations = make([]channel, 0, len(Config.Stations()))
+	var changedDays = 0
+	for _, channel := range Config.Stations() {
+		if dates, ok := changed[channel.ID]; ok {
+			channel.Date = dates
+			stations = append(stations, channel)
+			changedDays += len(dates)
+		}
 	}
 
-	for i, channel := range Config.Station {
+	logger.Info("Download Schedule", "days", len(days), "stations", len(stations), "changed", changedDays, "unchanged", unchanged)
+
+	for i, channel := range stations {
 
 		count++
 
-		channel.Date = days
 		channels = append(channels, channel)
 
-		if count == limit || i == len(Config.Station)-1 {
+		if count == limit || i == len(stations)-1 {
 
 			sd.Req.Data, err = json.Marshal(channels)
 			if err != nil {
@@ -161,16 +162,21 @@ func (sd *SD) GetData() {
 				return
 			}
 
-			sd.Schedule()
+			err = sd.Schedule()
+			if err != nil {
+				logger.Error("unable to download the schedule", "error", err)
+			} else {
+				var body = sd.Resp.Body
 
-			wg.Add(1)
-			go func() {
+				wg.Add(1)
+				go func() {
 
-				Cache.AddSchedule(&sd.Resp.Body)
+					Cache.AddSchedule(&body)
 
-				wg.Done()
+					wg.Done()
 
-			}()
+				}()
+			}
 
 			count = 0
 			channels = make([]interface{}, 0)
@@ -190,7 +196,9 @@ func (sd *SD) GetData() {
 	var allIDs = Cache.GetAllProgramIDs()
 	var programs = make([]interface{}, 0)
 
-	logger.Info("Download Program Informations", "new", len(programIds), "cached", len(allIDs)-len(programIds))
+	var changedIds = Cache.GetChangedProgramIDs(programIds)
+
+	logger.Info("Download Program Informations", "new", len(programIds)-len(changedIds), "changed", len(changedIds), "cached", len(allIDs)-len(programIds))
 
 	for _, t := range types {
 
@@ -256,3 +264,105 @@ func (sd *SD) GetData() {
 		return
 	}
 }
+
+// scheduleWindow returns the time range of programmes written to the XMLTV file:
+// "Schedule Days" days starting "Schedule Day Offset" days from today (UTC),
+// extended back by "Keep past programmes for hours" from now (or from the
+// start of the first day, if that lies in the future).
+func scheduleWindow(now time.Time) (from, to time.Time) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	first := today.AddDate(0, 0, max(Config.Options.ScheduleDayOffset, 0))
+	to = first.AddDate(0, 0, Config.Options.Schedule)
+
+	from = now
+	if first.After(now) {
+		from = first
+	}
+	from = from.Add(-time.Hour * time.Duration(max(Config.Options.PastHours, 0)))
+
+	return
+}
+
+// scheduleDays returns the SD schedule dates (UTC) covering from..to.
+func scheduleDays(from, to time.Time) (days []string) {
+	from, to = from.UTC(), to.UTC()
+	for d := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC); d.Before(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return
+}
+
+// changedScheduleDays asks Schedules Direct for the schedule MD5s and returns the
+// days per station whose MD5 differs from the cached one. If the MD5s can not be
+// retrieved, every day of every station is returned.
+func (sd *SD) changedScheduleDays(days []string) (changed map[string][]string, unchanged int) {
+
+	var limit = 5000
+	var count = 0
+	var md5s = make(SDScheduleMD5)
+	var channels = make([]interface{}, 0)
+
+	changed = make(map[string][]string)
+
+	var stations = Config.Stations()
+
+	for i, channel := range stations {
+
+		count++
+
+		channel.Date = days
+		channels = append(channels, channel)
+
+		if count == limit || i == len(stations)-1 {
+
+			var err error
+			var resp SDScheduleMD5
+
+			sd.Req.Data, err = json.Marshal(channels)
+			if err == nil {
+				err = sd.ScheduleMD5()
+			}
+			if err == nil {
+				err = json.Unmarshal(sd.Resp.Body, &resp)
+			}
+
+			if err != nil {
+				logger.Warn("unable to get the schedule MD5s; downloading all days", "error", err)
+				md5s = nil
+				break
+			}
+
+			for stationID, dates := range resp {
+				md5s[stationID] = dates
+			}
+
+			count = 0
+			channels = make([]interface{}, 0)
+
+		}
+
+	}
+
+	for _, channel := range stations {
+
+		for _, day := range days {
+
+			if md5s != nil {
+				if remote, ok := md5s[channel.ID][day]; ok && remote.Code == 0 && len(remote.Md5) != 0 {
+					if cached, ok := Cache.GetScheduleMD5(channel.ID, day); ok && cached == remote.Md5 {
+						unchanged++
+						continue
+					}
+				}
+			}
+
+			changed[channel.ID] = append(changed[channel.ID], day)
+
+		}
+
+	}
+
+	return
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleWindow(t *testing.T) {
+	original := Config
+	defer func() { Config = original }()
+
+	now := time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		days      int
+		offset    int
+		pastHours int
+		wantFrom  time.Time
+		wantTo    time.Time
+		wantDays  []string
+	}{
+		{
+			name:     "today",
+			days:     2,
+			wantFrom: now,
+			wantTo:   time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+			wantDays: []string{"2024-03-10", "2024-03-11"},
+		},
+		{
+			name:      "past hours reach into yesterday",
+			days:      1,
+			pastHours: 6,
+			wantFrom:  time.Date(2024, 3, 9, 21, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			wantDays:  []string{"2024-03-09", "2024-03-10"},
+		},
+		{
+			name:     "start tomorrow",
+			days:     2,
+			offset:   1,
+			wantFrom: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC),
+			wantDays: []string{"2024-03-11", "2024-03-12"},
+		},
+		{
+			name:      "negative values are ignored",
+			days:      1,
+			offset:    -2,
+			pastHours: -5,
+			wantFrom:  now,
+			wantTo:    time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			wantDays:  []string{"2024-03-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config.Options.Schedule = tt.days
+			Config.Options.ScheduleDayOffset = tt.offset
+			Config.Options.PastHours = tt.pastHours
+
+			from, to := scheduleWindow(now)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Fatalf("scheduleWindow() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+			if got := scheduleDays(from, to); !reflect.DeepEqual(got, tt.wantDays) {
+				t.Fatalf("scheduleDays() = %v, want %v", got, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestChangedScheduleDays(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	Cache.Lock()
+	originalMD5 := Cache.ScheduleMD5
+	Cache.ScheduleMD5 = map[string]map[string]string{
+		"90447": {"2024-03-10": "day1", "2024-03-11": "day2"},
+	}
+	Cache.Unlock()
+	defer func() {
+		Cache.Lock()
+		Cache.ScheduleMD5 = originalMD5
+		Cache.Unlock()
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Station = []channel{{ID: "90447"}, {ID: "12345"}}
+	days := []string{"2024-03-10", "2024-03-11"}
+
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/20141201/schedules/md5" {
+			t.Errorf("request path = %q", r.URL.Path)
+		}
+		if fail {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{
+			"90447": {"2024-03-10": {"code": 0, "md5": "day1"}, "2024-03-11": {"code": 0, "md5": "day2-new"}},
+			"12345": {"2024-03-10": {"code": 0, "md5": "other"}, "2024-03-11": {"code": 7100, "message": "not available"}}}`))
+	}))
+	defer srv.Close()
+
+	var sd SD
+	if err := sd.Init(); err != nil {
+		t.Fatalf("sd.Init() error = %v", err)
+	}
+	sd.BaseURL = srv.URL + "/20141201/"
+
+	// Only days with a cached, unchanged MD5 are skipped
+	changed, unchanged := sd.changedScheduleDays(days)
+	want := map[string][]string{"90447": {"2024-03-11"}, "12345": days}
+	if !reflect.DeepEqual(changed, want) || unchanged != 1 {
+		t.Errorf("changedScheduleDays() = %v, %d, want %v, 1", changed, unchanged, want)
+	}
+
+	// Without MD5s every day is downloaded
+	fail = true
+	changed, unchanged = sd.changedScheduleDays(days)
+	want = map[string][]string{"90447": days, "12345": days}
+	if !reflect.DeepEqual(changed, want) || unchanged != 0 {
+		t.Errorf("changedScheduleDays() without MD5s = %v, %d, want %v, 0", changed, unchanged, want)
+	}
+}
@@ -103,7 +103,13 @@ if [ "${RUN_ONCE}" = "true" ]; then
   exit 0
 fi
 
-# Case 2: CRON_SCHEDULE
+# Case 2: DAEMON (epgo refreshes itself on the "Refresh Schedule" from config.yaml)
+if [ "${DAEMON}" = "true" ]; then
+  echo "DAEMON is true. Running epgo in daemon mode (refresh schedule from config.yaml)..."
+  exec /sbin/su-exec "${PUID}:${PGID}" sh -c 'cd /app && exec /usr/bin/epgo -config /app/config.yaml -daemon'
+fi
+
+# Case 3: CRON_SCHEDULE
 if [ -n "${CRON_SCHEDULE}" ]; then
   echo "CRON_SCHEDULE is set. Configuring cron job..."
 
@@ -136,7 +142,7 @@ if [ -n "${CRON_SCHEDULE}" ]; then
   tail -f /dev/null
 fi
 
-# Case 3: Neither variable set
+# Case 4: Neither variable set
 echo "Error: No execution mode defined."
-echo "Please set either the CRON_SCHEDULE or RUN_ONCE environment variable."
+echo "Please set either the DAEMON, CRON_SCHEDULE or RUN_ONCE environment variable."
 exit 1
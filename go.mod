@@ -4,6 +4,8 @@ go 1.21
 
 require (
 	github.com/manifoldco/promptui v0.9.0
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/ulikunitz/xz v0.5.17
 	gopkg.in/yaml.v3 v3.0.1
 )
 
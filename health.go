@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// /healthz answers as long as the process serves HTTP. /readyz also checks
+// that the guide is fresh (Server: Readiness) and answers 503 otherwise.
+
+const (
+	defaultMaxXMLTVAgeHours  = 48
+	defaultAccountExpiryDays = 7
+)
+
+const (
+	checkOK      = "ok"
+	checkFail    = "fail"
+	checkUnknown = "unknown"
+)
+
+type readiness struct {
+	Status      string           `json:"status"`
+	XMLTV       xmltvCheck       `json:"xmltv"`
+	LastRefresh lastRefreshCheck `json:"lastRefresh"`
+	Account     accountCheck     `json:"account"`
+}
+
+type xmltvCheck struct {
+	Status      string     `json:"status"`
+	Message     string     `json:"message,omitempty"`
+	File        string     `json:"file"`
+	Modified    *time.Time `json:"modified,omitempty"`
+	AgeHours    float64    `json:"ageHours"`
+	MaxAgeHours int        `json:"maxAgeHours"`
+}
+
+type lastRefreshCheck struct {
+	Status   string     `json:"status"`
+	Message  string     `json:"message,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+type accountCheck struct {
+	Status   string     `json:"status"`
+	Message  string     `json:"message,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	DaysLeft float64    `json:"daysLeft"`
+	WarnDays int        `json:"warnDays"`
+}
+
+// checkReadiness evaluates the XMLTV file age, the last refresh and the SD
+// account expiry. Checks without data (e.g. before the first refresh) are unknown.
+func checkReadiness(now time.Time) (r readiness) {
+
+	r.Status = checkOK
+
+	var cfg = currentConfig()
+
+	// XMLTV file
+	r.XMLTV = xmltvCheck{
+		Status:      checkOK,
+		File:        cfg.Files.XMLTV,
+		MaxAgeHours: cfg.Server.Readiness.MaxXMLTVAgeHours,
+	}
+
+	if fi, err := os.Stat(cfg.Files.XMLTV); err != nil {
+		r.XMLTV.Status = checkFail
+		r.XMLTV.Message = "XMLTV file not found"
+	} else {
+		modified := fi.ModTime().UTC()
+		r.XMLTV.Modified = &modified
+		r.XMLTV.AgeHours = now.Sub(modified).Hours()
+
+		if maxAge := r.XMLTV.MaxAgeHours; maxAge > 0 && r.XMLTV.AgeHours > float64(maxAge) {
+			r.XMLTV.Status = checkFail
+			r.XMLTV.Message = fmt.Sprintf("XMLTV file is older than %d hours", maxAge)
+		}
+	}
+
+	// Last refresh
+	last := lastRefreshResult()
+
+	switch {
+	case last.Finished.IsZero():
+		r.LastRefresh = lastRefreshCheck{Status: checkUnknown, Message: "no refresh has finished since the start"}
+	case last.Err != nil:
+		finished := last.Finished.UTC()
+		r.LastRefresh = lastRefreshCheck{Status: checkFail, Message: "the last refresh failed", Finished: &finished, Error: last.Err.Error()}
+	default:
+		finished := last.Finished.UTC()
+		r.LastRefresh = lastRefreshCheck{Status: checkOK, Finished: &finished}
+	}
+
+	// SD account
+	r.Account = accountCheck{Status: checkOK, WarnDays: cfg.Server.Readiness.AccountExpiryDays}
+
+	if last.AccountExpires.IsZero() {
+		r.Account.Status = checkUnknown
+		r.Account.Message = "account status not known yet"
+	} else {
+		expires := last.AccountExpires.UTC()
+		r.Account.Expires = &expires
+		r.Account.DaysLeft = expires.Sub(now).Hours() / 24
+
+		if warn := r.Account.WarnDays; warn > 0 && r.Account.DaysLeft < float64(warn) {
+			r.Account.Status = checkFail
+			r.Account.Message = fmt.Sprintf("Schedules Direct account expires within %d days", warn)
+		}
+	}
+
+	for _, status := range []string{r.XMLTV.Status, r.LastRefresh.Status, r.Account.Status} {
+		if status == checkFail {
+			r.Status = checkFail
+		}
+	}
+
+	return
+}
+
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": checkOK})
+}
+
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+
+	result := checkReadiness(time.Now())
+
+	status := http.StatusOK
+	if result.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, result)
+}
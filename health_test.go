@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	original := Config
+	originalRefresh := lastRefreshResult()
+	defer func() {
+		Config = original
+		lastRefreshMu.Lock()
+		lastRefresh = originalRefresh
+		lastRefreshMu.Unlock()
+	}()
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	Config.Files.XMLTV = filepath.Join(dir, "config.xml")
+	if err := os.WriteFile(Config.Files.XMLTV, []byte("<tv></tv>"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := os.Chtimes(Config.Files.XMLTV, now.Add(-30*time.Hour), now.Add(-30*time.Hour)); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		maxAge      int
+		warnDays    int
+		refresh     refreshResult
+		wantStatus  string
+		wantXMLTV   string
+		wantRefresh string
+		wantAccount string
+	}{
+		{
+			name:        "ready",
+			maxAge:      48,
+			warnDays:    7,
+			refresh:     refreshResult{Finished: now.Add(-30 * time.Hour), AccountExpires: now.AddDate(0, 2, 0)},
+			wantStatus:  checkOK,
+			wantXMLTV:   checkOK,
+			wantRefresh: checkOK,
+			wantAccount: checkOK,
+		},
+		{
+			name:        "no refresh yet",
+			maxAge:      48,
+			warnDays:    7,
+			wantStatus:  checkOK,
+			wantXMLTV:   checkOK,
+			wantRefresh: checkUnknown,
+			wantAccount: checkUnknown,
+		},
+		{
+			name:        "stale XMLTV",
+			maxAge:      24,
+			warnDays:    7,
+			refresh:     refreshResult{Finished: now.Add(-30 * time.Hour), AccountExpires: now.AddDate(0, 2, 0)},
+			wantStatus:  checkFail,
+			wantXMLTV:   checkFail,
+			wantRefresh: checkOK,
+			wantAccount: checkOK,
+		},
+		{
+			name:        "failed refresh and expiring account",
+			maxAge:      0,
+			warnDays:    7,
+			refresh:     refreshResult{Finished: now.Add(-time.Hour), Err: errors.New("login failed"), AccountExpires: now.AddDate(0, 0, 3)},
+			wantStatus:  checkFail,
+			wantXMLTV:   checkOK,
+			wantRefresh: checkFail,
+			wantAccount: checkFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config.Server.Readiness.MaxXMLTVAgeHours = tt.maxAge
+			Config.Server.Readiness.AccountExpiryDays = tt.warnDays
+			lastRefreshMu.Lock()
+			lastRefresh = tt.refresh
+			lastRefreshMu.Unlock()
+
+			got := checkReadiness(now)
+
+			if got.Status != tt.wantStatus || got.XMLTV.Status != tt.wantXMLTV || got.LastRefresh.Status != tt.wantRefresh || got.Account.Status != tt.wantAccount {
+				t.Errorf("checkReadiness() = %s (xmltv %s, refresh %s, account %s), want %s (%s, %s, %s)",
+					got.Status, got.XMLTV.Status, got.LastRefresh.Status, got.Account.Status,
+					tt.wantStatus, tt.wantXMLTV, tt.wantRefresh, tt.wantAccount)
+			}
+		})
+	}
+}
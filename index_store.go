@@ -1,15 +1,14 @@
 package main
 
 import (
-	"bufio"
-	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"epgo/internal/atomicfile"
 )
 
 // ProgramID -> imageID persistent index used by the proxy to serve cached files
@@ -30,12 +29,6 @@ var (
 	indexImageRequests map[string]int64
 	indexLoaded        bool
 	indexPathV         string
-
-	overridesOnce     sync.Once
-	overridesPath     string
-	overridesEnabled  bool
-	overrideTitleToID map[string]string
-	overrideImageIDs  map[string]struct{}
 )
 
 func (e indexEntry) lastRequest() time.Time {
@@ -56,10 +49,17 @@ func preindexSDPosters() {
 	skipped := 0
 	updates := make(map[string]string)
 
+	Cache.RLock()
+	programIDs := make([]string, 0, len(Cache.Metadata))
 	for programID := range Cache.Metadata {
+		programIDs = append(programIDs, programID)
+	}
+	Cache.RUnlock()
+
+	for _, programID := range programIDs {
 		imageID := ""
-		if overrideID, ok := overrideImageForProgram(programID); ok {
-			imageID = overrideID
+		if override, ok := overrideImageForProgram(programID, ""); ok {
+			imageID = override.ImageID
 		} else if chosenID, _, ok := Cache.GetChosenSDImage(programID); ok {
 			imageID = chosenID
 		}
@@ -92,7 +92,7 @@ func preindexSDPosters() {
 
 func indexFilePath() string {
 	// Sidecar next to the cache file
-	p := Config.Files.Cache
+	p := currentConfig().Files.Cache
 	if p == "" {
 		// Fallback default within container
 		return "/app/config_cache.imgindex.json"
@@ -114,7 +114,9 @@ func indexInit() {
 		// Load if present
 		if data, err := os.ReadFile(indexPathV); err == nil && len(data) > 0 {
 			var raw map[string]json.RawMessage
-			if err := json.Unmarshal(data, &raw); err == nil {
+			if err := json.Unmarshal(data, &raw); err != nil {
+				logger.Warn("Index: image index is corrupt; rebuilding", "path", indexPathV, "error", err)
+			} else {
 				for programID, blob := range raw {
 					var entry indexEntry
 					if err := json.Unmarshal(blob, &entry); err == nil && entry.ImageID != "" {
@@ -138,115 +140,6 @@ func indexInit() {
 	})
 }
 
-func overridesFilePath() string {
-	return filepath.Join(filepath.Dir(indexFilePath()), "overrides.txt")
-}
-
-func overridesInit() {
-	overridesOnce.Do(func() {
-		overridesPath = overridesFilePath()
-		overrideTitleToID = map[string]string{}
-		overrideImageIDs = map[string]struct{}{}
-
-		data, err := os.ReadFile(overridesPath)
-		if err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				logger.Warn("Overrides: failed to read overrides file", "path", overridesPath, "error", err)
-			}
-			return
-		}
-
-		scanner := bufio.NewScanner(strings.NewReader(string(data)))
-		lineNo := 0
-		for scanner.Scan() {
-			lineNo++
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
-
-			r := csv.NewReader(strings.NewReader(line))
-			r.TrimLeadingSpace = true
-			record, err := r.Read()
-			if err != nil {
-				logger.Warn("Overrides: unable to parse line", "path", overridesPath, "line", lineNo, "error", err)
-				continue
-			}
-			if len(record) != 2 {
-				logger.Warn("Overrides: invalid record length", "path", overridesPath, "line", lineNo, "fields", len(record))
-				continue
-			}
-
-			title := strings.TrimSpace(record[0])
-			imageID := strings.TrimSpace(record[1])
-			if title == "" || imageID == "" {
-				logger.Warn("Overrides: empty title or imageID", "path", overridesPath, "line", lineNo)
-				continue
-			}
-
-			normTitle := strings.ToLower(title)
-
-			overrideTitleToID[normTitle] = imageID
-			overrideImageIDs[imageID] = struct{}{}
-		}
-
-		if len(overrideTitleToID) > 0 {
-			overridesEnabled = true
-			logger.Info("Overrides: loaded image overrides", "count", len(overrideTitleToID), "path", overridesPath)
-		}
-	})
-}
-
-func overrideImageForTitle(title string) (string, bool) {
-	overridesInit()
-	if !overridesEnabled {
-		return "", false
-	}
-	normTitle := strings.ToLower(strings.TrimSpace(title))
-	imageID, ok := overrideTitleToID[normTitle]
-	return imageID, ok
-}
-
-func overrideImageForProgram(programID string) (string, bool) {
-	overridesInit()
-	if !overridesEnabled {
-		return "", false
-	}
-
-	p, ok := Cache.Program[programID]
-	if !ok {
-		return "", false
-	}
-
-	for _, t := range p.Titles {
-		if imageID, ok := overrideImageForTitle(t.Title120); ok {
-			return imageID, true
-		}
-	}
-
-	return "", false
-}
-
-// overrideImageForProgramOrTitle attempts to resolve an override for a program.
-// It first checks the cached program metadata (for Title120 matches) and, if that
-// fails, it will also attempt to match the provided fallbackTitle (e.g. from a
-// schedule entry) so overrides still work when program metadata is missing.
-func overrideImageForProgramOrTitle(programID, fallbackTitle string) (string, bool) {
-	if id, ok := overrideImageForProgram(programID); ok {
-		return id, true
-	}
-	return overrideImageForTitle(fallbackTitle)
-}
-
-func isOverrideImageID(imageID string) bool {
-	overridesInit()
-	if !overridesEnabled || imageID == "" {
-		return false
-	}
-	_, ok := overrideImageIDs[imageID]
-	return ok
-}
-
 // isSDImageID returns true if the identifier looks like an SD-hosted asset (no URL/path bits).
 func isSDImageID(imageID string) bool {
 	if imageID == "" {
@@ -254,7 +147,7 @@ func isSDImageID(imageID string) bool {
 	}
 
 	low := strings.ToLower(imageID)
-	if strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://") {
+	if strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://") || strings.HasPrefix(low, overrideFilePrefix) {
 		return false
 	}
 	if strings.Contains(low, "tmdb") {
@@ -277,7 +170,7 @@ func indexSave() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(indexPathV, blob, 0644)
+	return atomicfile.WriteFile(indexPathV, blob, 0644)
 }
 
 func indexGet(programID string) (string, bool) {
@@ -0,0 +1,104 @@
+// Package atomicfile writes files crash-safe: data goes to a temporary file
+// next to the target, which only replaces the target on Commit (fsync +
+// rename). Readers never see a half-written file, and a process killed
+// mid-write leaves the previous version intact.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// File is a temporary file that replaces its target on Commit.
+type File struct {
+	*os.File
+	path string
+	perm os.FileMode
+	done bool
+}
+
+// Create opens a temporary file in the directory of path.
+func Create(path string, perm os.FileMode) (*File, error) {
+	dir := filepath.Dir(path)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{File: f, path: path, perm: perm}, nil
+}
+
+// Commit flushes the temporary file to disk and renames it over the target.
+func (f *File) Commit() (err error) {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	tmp := f.File.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if err = f.File.Chmod(f.perm); err != nil {
+		f.File.Close()
+		return
+	}
+	if err = f.File.Sync(); err != nil {
+		f.File.Close()
+		return
+	}
+	if err = f.File.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, f.path); err != nil {
+		return
+	}
+
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Abort discards the temporary file. It is a no-op after Commit, so it can be deferred.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.File.Close()
+	_ = os.Remove(f.File.Name())
+}
+
+// WriteFile is the crash-safe counterpart of os.WriteFile.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+
+	return f.Commit()
+}
+
+// syncDir persists a rename in dir. Errors are ignored: not every platform
+// supports fsync on directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
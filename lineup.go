@@ -1 +1,107 @@
 package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// lineupIDPattern matches SD lineup IDs such as USA-NY12345-X or DEU-1000097-DEFAULT.
+var lineupIDPattern = regexp.MustCompile(`^[A-Z]{3}-[A-Za-z0-9-]+$`)
+
+// isLineupID reports whether lineup is an SD lineup ID and therefore safe to
+// use in the path of a lineups request.
+func isLineupID(lineup string) bool {
+	return lineupIDPattern.MatchString(lineup)
+}
+
+// knownRegions keeps the order of the Add Lineup menu from before the countries
+// response was generic. Other regions (e.g. Oceania) follow alphabetically.
+var knownRegions = []string{"North America", "Europe", "Latin America", "Caribbean"}
+
+// countryRegions returns the regions of the available/countries response in menu order.
+func countryRegions(countries map[string][]SDCountry) (regions []string) {
+
+	var rank = func(region string) int {
+		for i, r := range knownRegions {
+			if r == region {
+				return i
+			}
+		}
+		return len(knownRegions)
+	}
+
+	for region := range countries {
+		regions = append(regions, region)
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		ri, rj := rank(regions[i]), rank(regions[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return regions[i] < regions[j]
+	})
+
+	return
+}
+
+// headendLineups returns the lineups of the headends (postal code) response.
+func headendLineups(sd *SD) (lineups []Entry) {
+
+	for _, headend := range sd.Resp.Headend {
+		for _, lineup := range headend.Lineups {
+			lineups = append(lineups, Entry{
+				Value:     fmt.Sprintf("%s [%s]", lineup.Name, lineup.Lineup),
+				Lineup:    lineup.Lineup,
+				Transport: headend.Transport,
+			})
+		}
+	}
+
+	return
+}
+
+// satelliteLineups returns the DVB-S lineups (available/dvb-s).
+func satelliteLineups(sd *SD) (lineups []Entry, err error) {
+
+	err = sd.Satellites()
+	if err != nil {
+		return
+	}
+
+	for _, satellite := range sd.Resp.Satellites {
+		lineups = append(lineups, Entry{
+			Value:     satellite.Lineup,
+			Lineup:    satellite.Lineup,
+			Transport: "DVB-S",
+		})
+	}
+
+	return
+}
+
+// transmitterLineups returns the antenna lineups of a country
+// (transmitters/{country}), sorted by transmitter name.
+// sd.Req.Parameter has to be "/<ISO 3166-1 alpha-3 country code>".
+func transmitterLineups(sd *SD) (lineups []Entry, err error) {
+
+	err = sd.Transmitters()
+	if err != nil {
+		return
+	}
+
+	for name, lineup := range sd.Resp.Transmitters {
+		lineups = append(lineups, Entry{
+			Value:     fmt.Sprintf("%s [%s]", name, lineup),
+			Lineup:    lineup,
+			Transport: "Antenna",
+		})
+	}
+
+	sort.Slice(lineups, func(i, j int) bool {
+		return lineups[i].Value < lineups[j].Value
+	})
+
+	return
+}
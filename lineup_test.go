@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCountryRegions(t *testing.T) {
+
+	countries := map[string][]SDCountry{
+		"Oceania":       {{FullName: "Australia", ShortName: "AUS"}},
+		"Europe":        {{FullName: "Germany", ShortName: "DEU"}},
+		"Asia":          {{FullName: "Japan", ShortName: "JPN"}},
+		"North America": {{FullName: "United States", ShortName: "USA"}},
+		"Caribbean":     {{FullName: "Jamaica", ShortName: "JAM"}},
+	}
+
+	got := countryRegions(countries)
+	want := []string{"North America", "Europe", "Caribbean", "Asia", "Oceania"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("countryRegions() = %v, want %v", got, want)
+	}
+}
+
+func TestTransmitterLineups(t *testing.T) {
+	originalLogger := logger
+	defer func() { logger = originalLogger }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"Crystal Palace": "GBR-1000014-DEFAULT", "Black Hill": "GBR-1000002-DEFAULT"}`))
+	}))
+	defer srv.Close()
+
+	var sd SD
+	if err := sd.Init(); err != nil {
+		t.Fatalf("sd.Init() error = %v", err)
+	}
+	sd.BaseURL = srv.URL + "/20141201/"
+	sd.Req.Parameter = "/GBR"
+
+	got, err := transmitterLineups(&sd)
+	if err != nil {
+		t.Fatalf("transmitterLineups() error = %v", err)
+	}
+
+	if want := "/20141201/transmitters/GBR"; path != want {
+		t.Errorf("request path = %q, want %q", path, want)
+	}
+
+	want := []Entry{
+		{Value: "Black Hill [GBR-1000002-DEFAULT]", Lineup: "GBR-1000002-DEFAULT", Transport: "Antenna"},
+		{Value: "Crystal Palace [GBR-1000014-DEFAULT]", Lineup: "GBR-1000014-DEFAULT", Transport: "Antenna"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transmitterLineups() = %+v, want %+v", got, want)
+	}
+}
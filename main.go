@@ -10,10 +10,10 @@ import (
 
 // Names (base = your fork, non-base = upstream)
 const (
-        AppName     = "EPGo-Docker" // your Docker fork
-        Version     = "v1.3.4"
-        BaseName    = "EPGo" // upstream app
-        BaseVersion = "v3.2.1"
+	AppName     = "EPGo-Docker" // your Docker fork
+	Version     = "v1.3.4"
+	BaseName    = "EPGo" // upstream app
+	BaseVersion = "v3.2.1"
 )
 
 // Config : Config file (struct)
@@ -26,13 +26,26 @@ func main() {
 	var config = flag.String("config", "", "= Get data from Schedules Direct with configuration file. [filename.yaml]")
 	var version = flag.Bool("version", false, "= Get version")
 	var serve = flag.String("serve", "", "= Start a local HTTP server to serve files from the specified directory. [directory:port]")
+	var daemon = flag.Bool("daemon", false, "= Keep running with -config and refresh on the 'Refresh Schedule' from the configuration file")
+	var output = flag.String("output", "table", "= Output format of the lineup, headends and channels commands. [table|json]")
 	var h = flag.Bool("h", false, ": Show help")
 
-	logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		printCommandUsage(flag.CommandLine.Output())
+	}
 
 	flag.Parse()
 	Config2 = *config
 
+	// Commands print their result to stdout, so the log goes to stderr
+	if flag.NArg() != 0 {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	} else {
+		logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	}
+
 	// Startup banner
 	logger.Info(fmt.Sprintf("%s starting", AppName),
 		"version", Version,
@@ -59,6 +72,18 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Commands: epgo -config file.yaml lineup|headends|channels ...
+	if flag.NArg() != 0 {
+		if err := RunCommand(*config, flag.Args(), *output); err != nil {
+			logger.Error("command failed", "command", strings.Join(flag.Args(), " "), "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Graceful shutdown on SIGTERM/SIGINT, reload on SIGHUP
+	handleSignals()
+
 	// Standalone file server mode: epgo -serve dir:port
 	if len(*serve) != 0 {
 		parts := strings.Split(*serve, ":")
@@ -72,14 +97,20 @@ func main() {
 		return
 	}
 
+	// Daemon mode: epgo -config file.yaml -daemon
+	if len(*config) != 0 && *daemon {
+		if err := RunDaemon(*config); err != nil {
+			logger.Error("unable to start the refresh scheduler", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Normal mode: epgo -config file.yaml
 	if len(*config) != 0 {
-		var sd SD
 		// Try to grab EPG; even if it fails, we may still start the proxy (if enabled).
-		err := sd.Update(*config)
-		if err != nil {
-			logger.Error("unable to get data from Schedules Direct", "error", err)
-		}
+		// runRefresh logs the result and records it for /api/status.
+		err := runRefresh(*config)
 
 		// If Server.Enable is true, start the proxy regardless of EPG result.
 		if Config.Server.Enable {
@@ -55,51 +55,28 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 	entry.Value = getMsg(0200)
 	menu.Entry[index] = entry
 
-	for _, lineup := range sd.Resp.Countries.NorthAmerica {
-
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
-
-	}
-
-	for _, lineup := range sd.Resp.Countries.Europe {
-
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
-
-	}
+	// Satellite (DVB-S)
+	index++
+	entry.Key = index
+	entry.Value = getMsg(0205)
+	entry.Transport = "DVB-S"
+	menu.Entry[index] = entry
 
-	for _, lineup := range sd.Resp.Countries.LatinAmerica {
+	entry.Transport = ""
 
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
+	for _, region := range countryRegions(sd.Resp.Countries) {
 
-	}
+		for _, country := range sd.Resp.Countries[region] {
 
-	for _, lineup := range sd.Resp.Countries.Caribbean {
+			index++
+			entry.Key = index
+			entry.Value = fmt.Sprintf("%s: %s [%s]", region, country.FullName, country.PostalCodeExample)
+			entry.Country = country.FullName
+			entry.Postalcode = country.PostalCode
+			entry.ShortName = country.ShortName
+			menu.Entry[index] = entry
 
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
+		}
 
 	}
 
@@ -116,19 +93,42 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 
 	fmt.Println(entry.Value)
 
-	for {
+	var lineups []Entry
+
+	switch {
 
-		fmt.Printf("%s: ", getMsg(0202))
-		fmt.Scanln(&postalcode)
+	case entry.Transport == "DVB-S":
+		lineups, err = satelliteLineups(sd)
+		if err != nil {
+			return
+		}
+
+	case chooseTransmitters(entry):
+		sd.Req.Parameter = fmt.Sprintf("/%s", entry.ShortName)
+
+		lineups, err = transmitterLineups(sd)
+		if err != nil {
+			return
+		}
+
+	default:
+		for {
+
+			fmt.Printf("%s: ", getMsg(0202))
+			fmt.Scanln(&postalcode)
 
-		sd.Req.Parameter = fmt.Sprintf("?country=%s&postalcode=%s", entry.ShortName, postalcode)
+			sd.Req.Parameter = fmt.Sprintf("?country=%s&postalcode=%s", entry.ShortName, postalcode)
 
-		err = sd.Headends()
+			err = sd.Headends()
+
+			if err == nil {
+				break
+			}
 
-		if err == nil {
-			break
 		}
 
+		lineups = headendLineups(sd)
+
 	}
 
 	// Select Linup
@@ -142,18 +142,11 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 	entry.Value = getMsg(0200)
 	menu.Entry[index] = entry
 
-	for _, slice := range sd.Resp.Headend {
+	for _, lineup := range lineups {
 
-		for _, lineup := range slice.Lineups {
-
-			index++
-			entry.Key = index
-			entry.Value = fmt.Sprintf("%s [%s]", lineup.Name, lineup.Lineup)
-			entry.Lineup = lineup.Lineup
-
-			menu.Entry[index] = entry
-
-		}
+		index++
+		lineup.Key = index
+		menu.Entry[index] = lineup
 
 	}
 
@@ -176,6 +169,28 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 	return
 }
 
+// chooseTransmitters asks whether to add a lineup by postal code (cable, IPTV,
+// antenna) or from the antenna transmitters of the country.
+func chooseTransmitters(country Entry) bool {
+
+	var menu Menu
+	var entry Entry
+
+	menu.Entry = make(map[int]Entry)
+	menu.Select = getMsg(0001)
+	menu.Headline = country.Country
+
+	entry.Key = 0
+	entry.Value = getMsg(0206)
+	menu.Entry[0] = entry
+
+	entry.Key = 1
+	entry.Value = getMsg(0207)
+	menu.Entry[1] = entry
+
+	return menu.Show() == 1
+}
+
 func (e *Entry) removeLineup(sd *SD) (err error) {
 
 	var index, selection int
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"epgo/tmdb"
+)
+
+// Prometheus metrics on /metrics (text exposition format 0.0.4). Counters are
+// kept in memory and reset when the process restarts.
+
+// counterVec is a counter with one label and a fixed set of label values.
+type counterVec struct {
+	label  string
+	values []string
+	counts map[string]*atomic.Uint64
+}
+
+func newCounterVec(label string, values ...string) *counterVec {
+	c := &counterVec{label: label, values: values, counts: make(map[string]*atomic.Uint64, len(values))}
+	for _, v := range values {
+		c.counts[v] = new(atomic.Uint64)
+	}
+	return c
+}
+
+func (c *counterVec) inc(value string) {
+	if n, ok := c.counts[value]; ok {
+		n.Add(1)
+	}
+}
+
+func (c *counterVec) get(value string) uint64 {
+	if n, ok := c.counts[value]; ok {
+		return n.Load()
+	}
+	return 0
+}
+
+var (
+	metricProxyRequests        = newCounterVec("outcome", "cache_hit", "index_hit", "download", "not_found", "rate_limited", "error")
+	metricImageDownloads       = newCounterVec("result", "ok", "error")
+	metricTokenRefreshes       = newCounterVec("result", "ok", "error")
+	metricForcedTokenRefreshes atomic.Uint64
+)
+
+func recordImageDownload(ok bool) {
+	if ok {
+		metricImageDownloads.inc("ok")
+	} else {
+		metricImageDownloads.inc("error")
+	}
+}
+
+func recordTokenRefresh(ok bool) {
+	if ok {
+		metricTokenRefreshes.inc("ok")
+	} else {
+		metricTokenRefreshes.inc("error")
+	}
+}
+
+// proxyRecorder remembers the status and the outcome of a proxy response.
+type proxyRecorder struct {
+	http.ResponseWriter
+	status  int
+	outcome string
+}
+
+func (p *proxyRecorder) WriteHeader(code int) {
+	if p.status == 0 {
+		p.status = code
+	}
+	p.ResponseWriter.WriteHeader(code)
+}
+
+func (p *proxyRecorder) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// markProxyOutcome labels how the proxy is about to answer successfully.
+func markProxyOutcome(w http.ResponseWriter, outcome string) {
+	if p, ok := w.(*proxyRecorder); ok {
+		p.outcome = outcome
+	}
+}
+
+// instrumentProxy counts proxy requests by outcome; error statuses win over the marked outcome.
+func instrumentProxy(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &proxyRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		switch {
+		case rec.status == http.StatusNotFound:
+			metricProxyRequests.inc("not_found")
+		case rec.status == http.StatusTooManyRequests:
+			metricProxyRequests.inc("rate_limited")
+		case rec.status >= 400 || len(rec.outcome) == 0:
+			metricProxyRequests.inc("error")
+		default:
+			metricProxyRequests.inc(rec.outcome)
+		}
+	})
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
+
+func writeCounterVec(w io.Writer, name, help string, c *counterVec) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, v := range c.values {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, c.label, v, c.get(v))
+	}
+}
+
+// imageCacheUsage returns the number and total size of the cached images.
+func imageCacheUsage() (files int, bytes int64) {
+
+	folderImage := currentConfig().Options.Images.Path
+	if folderImage == "" {
+		folderImage = "images"
+	}
+
+	entries, err := os.ReadDir(folderImage)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jpg") {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			files++
+			bytes += fi.Size()
+		}
+	}
+
+	return
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeCounterVec(w, "epgo_proxy_requests_total", "Image proxy requests by outcome.", metricProxyRequests)
+	writeCounterVec(w, "epgo_sd_image_downloads_total", "Image downloads from Schedules Direct by result.", metricImageDownloads)
+	writeCounterVec(w, "epgo_sd_token_refreshes_total", "Schedules Direct logins for a new token by result.", metricTokenRefreshes)
+	writeMetric(w, "epgo_sd_token_forced_refreshes_total", "counter", "Forced token refreshes after SD rejected the token.", metricForcedTokenRefreshes.Load())
+
+	var active, remaining float64
+	if paused, left := shouldBlockGlobal(); paused {
+		active, remaining = 1, left.Seconds()
+	}
+	writeMetric(w, "epgo_image_fetch_pause_active", "gauge", "1 while image downloads are paused after SD limits.", active)
+	writeMetric(w, "epgo_image_fetch_pause_remaining_seconds", "gauge", "Seconds until the image download pause ends.", remaining)
+
+	Cache.RLock()
+	programs, metadata := len(Cache.Program), len(Cache.Metadata)
+	Cache.RUnlock()
+
+	indexMu.RLock()
+	indexEntries := len(indexMap)
+	indexMu.RUnlock()
+
+	files, size := imageCacheUsage()
+
+	writeMetric(w, "epgo_cache_programs", "gauge", "Programmes in the cache file.", programs)
+	writeMetric(w, "epgo_cache_metadata", "gauge", "Programme metadata (artwork) entries in the cache file.", metadata)
+	writeMetric(w, "epgo_image_index_entries", "gauge", "ProgramID to imageID entries in the image index.", indexEntries)
+	writeMetric(w, "epgo_image_cache_files", "gauge", "Cached image files on disk.", files)
+	writeMetric(w, "epgo_image_cache_bytes", "gauge", "Size of the cached image files on disk.", size)
+
+	last := lastRefreshResult()
+	var duration, timestamp, success float64
+	if !last.Finished.IsZero() {
+		duration = last.Finished.Sub(last.Started).Seconds()
+		timestamp = float64(last.Finished.UnixNano()) / float64(time.Second)
+		if last.Err == nil {
+			success = 1
+		}
+	}
+	writeMetric(w, "epgo_last_refresh_duration_seconds", "gauge", "Duration of the last EPG refresh.", duration)
+	writeMetric(w, "epgo_last_refresh_timestamp_seconds", "gauge", "Unix time the last EPG refresh finished (0 before the first one).", timestamp)
+	writeMetric(w, "epgo_last_refresh_success", "gauge", "1 if the last EPG refresh succeeded.", success)
+
+	stats := tmdb.GetStats()
+	fmt.Fprintf(w, "# HELP epgo_tmdb_lookups_total TMDb poster lookups by result.\n# TYPE epgo_tmdb_lookups_total counter\n")
+	fmt.Fprintf(w, "epgo_tmdb_lookups_total{result=\"cache_hit\"} %d\n", stats.CacheHits)
+	fmt.Fprintf(w, "epgo_tmdb_lookups_total{result=\"hit\"} %d\n", stats.Hits)
+	fmt.Fprintf(w, "epgo_tmdb_lookups_total{result=\"miss\"} %d\n", stats.Misses)
+	fmt.Fprintf(w, "epgo_tmdb_lookups_total{result=\"error\"} %d\n", stats.Errors)
+}
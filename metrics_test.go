@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentProxy(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name: "cache hit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				markProxyOutcome(w, "cache_hit")
+				w.Write([]byte("jpg"))
+			},
+			want: "cache_hit",
+		},
+		{
+			name: "marked but not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				markProxyOutcome(w, "index_hit")
+				http.NotFound(w, r)
+			},
+			want: "not_found",
+		},
+		{
+			name: "paused",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "paused", http.StatusTooManyRequests)
+			},
+			want: "rate_limited",
+		},
+		{
+			name: "upstream error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "fetch failed", http.StatusBadGateway)
+			},
+			want: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := metricProxyRequests.get(tt.want)
+
+			rec := httptest.NewRecorder()
+			instrumentProxy(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proxy/sd/SH123", nil))
+
+			if got := metricProxyRequests.get(tt.want) - before; got != 1 {
+				t.Errorf("outcome %q counted %d times, want 1", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	original := Config
+	defer func() { Config = original }()
+
+	Config.Options.Images.Path = t.TempDir()
+
+	rec := httptest.NewRecorder()
+	metricsHandler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`epgo_proxy_requests_total{outcome="cache_hit"}`,
+		`epgo_sd_image_downloads_total{result="error"}`,
+		"epgo_sd_token_forced_refreshes_total ",
+		"epgo_image_fetch_pause_active 0",
+		"epgo_image_cache_bytes 0",
+		"epgo_last_refresh_timestamp_seconds ",
+		`epgo_tmdb_lookups_total{result="miss"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) != 2 {
+			t.Errorf("malformed sample line %q", line)
+		}
+	}
+}
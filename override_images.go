@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"epgo/internal/atomicfile"
+)
+
+// Poster overrides can use your own artwork instead of an SD image ID:
+//
+//	file:<name>   an image in the overrides folder next to overrides.txt
+//	http(s)://... an image that is downloaded once into the image folder
+//
+// The proxy serves them on /proxy/sd/{programID} like SD art.
+
+const (
+	overrideFilePrefix   = "file:"
+	overrideCachePrefix  = "override-"
+	maxOverrideImageSize = 20 << 20
+)
+
+// overrideImageExtensions maps the sniffed content type to the file extension.
+var overrideImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+func overrideImagesDir() string {
+	return filepath.Join(filepath.Dir(overridesFilePath()), "overrides")
+}
+
+// overrideLocalFile returns the path of a file: override.
+func overrideLocalFile(imageID string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(imageID), overrideFilePrefix) {
+		return "", false
+	}
+	return filepath.Join(overrideImagesDir(), imageID[len(overrideFilePrefix):]), true
+}
+
+// overrideURL returns the URL of an http(s) override.
+func overrideURL(imageID string) (string, bool) {
+	low := strings.ToLower(imageID)
+	if strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://") {
+		return imageID, true
+	}
+	return "", false
+}
+
+// overrideCacheName is the file name (without extension) of a downloaded URL override.
+func overrideCacheName(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return overrideCachePrefix + hex.EncodeToString(sum[:8])
+}
+
+// checkOverrideImage reports imageIDs that are neither an SD image ID nor a
+// usable file: name or URL.
+func checkOverrideImage(imageID string) error {
+
+	if name, ok := strings.CutPrefix(strings.ToLower(imageID), overrideFilePrefix); ok {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("invalid file %q: expected a file name in the overrides folder", imageID[len(overrideFilePrefix):])
+		}
+		return nil
+	}
+
+	if _, ok := overrideURL(imageID); ok {
+		if u, err := url.Parse(imageID); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid URL %q", imageID)
+		}
+		return nil
+	}
+
+	if !isSDImageID(imageID) {
+		return fmt.Errorf("invalid imageID %q: expected an SD image ID, file:<name> or an http(s) URL", imageID)
+	}
+
+	return nil
+}
+
+// cachedOverrideImage finds the downloaded copy of a URL override.
+func cachedOverrideImage(folderImage, rawURL string) (string, bool) {
+	base := filepath.Join(folderImage, overrideCacheName(rawURL))
+	for _, ext := range overrideImageExtensions {
+		if fi, err := os.Stat(base + ext); err == nil && !fi.IsDir() {
+			return base + ext, true
+		}
+	}
+	return "", false
+}
+
+// fetchOverrideImage downloads a URL override into folderImage.
+func fetchOverrideImage(folderImage, rawURL string) (filePath string, err error) {
+	defer func() { recordImageDownload(err == nil) }()
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOverrideImageSize+1))
+	if err != nil {
+		return
+	}
+	if len(body) > maxOverrideImageSize {
+		err = fmt.Errorf("image larger than %d MB", maxOverrideImageSize>>20)
+		return
+	}
+
+	ext, ok := overrideImageExtensions[http.DetectContentType(body)]
+	if !ok || !looksLikeImage(body) {
+		err = errors.New("not a JPEG, PNG, WebP or GIF image")
+		return
+	}
+
+	filePath = filepath.Join(folderImage, overrideCacheName(rawURL)+ext)
+	err = atomicfile.WriteFile(filePath, body, 0644)
+	return
+}
+
+// serveOverrideImage answers a proxy request whose override is a file: name
+// or a URL.
+func serveOverrideImage(w http.ResponseWriter, r *http.Request, folderImage, programID, imageID string) {
+
+	if filePath, ok := overrideLocalFile(imageID); ok {
+		if fi, err := os.Stat(filePath); err != nil || fi.IsDir() {
+			logger.Warn("Proxy: override image file not found", "programID", programID, "path", filePath)
+			http.NotFound(w, r)
+			return
+		}
+		logger.Info("Proxy: serve override image file", "programID", programID, "path", filePath)
+		markProxyOutcome(w, "cache_hit")
+		serveFileCached(w, r, filePath)
+		return
+	}
+
+	rawURL, _ := overrideURL(imageID)
+
+	if filePath, ok := cachedOverrideImage(folderImage, rawURL); ok {
+		logger.Info("Proxy: serve override image from cache", "programID", programID, "url", rawURL, "path", filePath)
+		markProxyOutcome(w, "cache_hit")
+		serveFileCached(w, r, filePath)
+		return
+	}
+
+	logger.Info("Proxy: downloading override image", "programID", programID, "url", rawURL)
+
+	filePath, err := fetchOverrideImage(folderImage, rawURL)
+	if err != nil {
+		logger.Warn("Proxy: override image download failed", "programID", programID, "url", rawURL, "error", err)
+		http.Error(w, "override image download failed", http.StatusBadGateway)
+		return
+	}
+
+	logger.Info("Proxy: serve freshly cached override image", "programID", programID, "url", rawURL, "path", filePath)
+	markProxyOutcome(w, "download")
+	serveFileCached(w, r, filePath)
+}
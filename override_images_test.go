@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+
+func TestCheckOverrideImage(t *testing.T) {
+	tests := []struct {
+		imageID string
+		wantErr bool
+	}{
+		{imageID: "p301122_b_v8"},
+		{imageID: "file:simpsons.png"},
+		{imageID: "FILE:Simpsons.jpg"},
+		{imageID: "https://example.com/posters/simpsons.jpg"},
+		{imageID: "file:../config.yaml", wantErr: true},
+		{imageID: "file:", wantErr: true},
+		{imageID: "https://", wantErr: true},
+		{imageID: "posters/simpsons.jpg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := checkOverrideImage(tt.imageID); (err != nil) != tt.wantErr {
+			t.Errorf("checkOverrideImage(%q) error = %v, wantErr %v", tt.imageID, err, tt.wantErr)
+		}
+	}
+}
+
+func TestServeOverrideImage(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+	folderImage := filepath.Join(dir, "images")
+	if err := os.MkdirAll(folderImage, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() error = %v", err)
+	}
+
+	downloads := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if r.URL.Path == "/not-an-image" {
+			w.Write([]byte("<html>nope</html>"))
+			return
+		}
+		w.Write(testPNG)
+	}))
+	defer upstream.Close()
+
+	// Local file
+	if err := os.MkdirAll(overrideImagesDir(), 0755); err != nil {
+		t.Fatalf("os.MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(overrideImagesDir(), "simpsons.png"), testPNG, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	serve := func(imageID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		serveOverrideImage(rec, httptest.NewRequest(http.MethodGet, "/proxy/sd/EP012345670001", nil), folderImage, "EP012345670001", imageID)
+		return rec
+	}
+
+	rec := serve("file:simpsons.png")
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), testPNG) || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("file override: status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	if rec := serve("file:missing.png"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing file override: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// URL: downloaded once, then served from the image folder
+	posterURL := upstream.URL + "/simpsons"
+	for i := 0; i < 2; i++ {
+		if rec := serve(posterURL); rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), testPNG) {
+			t.Errorf("URL override request %d: status = %d", i+1, rec.Code)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloads = %d, want 1", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(folderImage, overrideCacheName(posterURL)+".png")); err != nil {
+		t.Errorf("downloaded override not cached: %v", err)
+	}
+
+	if rec := serve(upstream.URL + "/not-an-image"); rec.Code != http.StatusBadGateway {
+		t.Errorf("non-image URL: status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+
+	// Cached URL overrides are exempt from the purge
+	if err := os.WriteFile(overridesFilePath(), []byte("The Simpsons,"+posterURL+"\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	overridesReload()
+	if !isOverrideImageID(overrideCacheName(posterURL)) {
+		t.Errorf("isOverrideImageID() = false for the cached URL override")
+	}
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"epgo/internal/atomicfile"
+)
+
+// Poster overrides from overrides.txt, one CSV line per override:
+//
+//	match,imageID[,stationID]
+//
+// match is a Title120 or one of program:<programID>, series:<SH root>,
+// regex:<expression> and title:<Title120>. imageID is an SD image ID or your
+// own image (see override_images.go). A stationID limits the override to one
+// station.
+//
+// Precedence: overrides for the station win over overrides without a station.
+// Within each, a programID wins over the series, the series over an exact
+// title and an exact title over a regex. Regexes are tried in file order.
+
+const (
+	overrideByProgram = "program"
+	overrideBySeries  = "series"
+	overrideByTitle   = "title"
+	overrideByRegex   = "regex"
+)
+
+var (
+	overridesOnce    sync.Once
+	overridesMu      sync.RWMutex
+	overridesPath    string
+	overridesEnabled bool
+	overrideKeyed    map[overrideKey]string // program, series and title overrides
+	overrideRegexps  []overrideRule
+	overrideImageIDs map[string]struct{}
+)
+
+type overrideKey struct {
+	Kind    string
+	Key     string
+	Station string
+}
+
+// overrideRule is a parsed line of the overrides file.
+type overrideRule struct {
+	overrideKey
+	ImageID string
+	re      *regexp.Regexp
+}
+
+// String is the rule as used in logs, e.g. "the simpsons" or "series:SH01234567@10001".
+func (r overrideRule) String() string {
+	s := r.Kind + ":" + r.Key
+	if r.Kind == overrideByTitle {
+		s = r.Key
+	}
+	if len(r.Station) != 0 {
+		s += "@" + r.Station
+	}
+	return s
+}
+
+// overrideProblem is a line of the overrides file that was skipped.
+type overrideProblem struct {
+	Line int
+	Err  error
+}
+
+// overrideRecord is one line of the overrides file. Title holds the match,
+// with its prefix.
+type overrideRecord struct {
+	Title   string `json:"title"`
+	ImageID string `json:"imageID"`
+	Station string `json:"station,omitempty"`
+}
+
+func overridesFilePath() string {
+	return filepath.Join(filepath.Dir(indexFilePath()), "overrides.txt")
+}
+
+func overridesInit() {
+	overridesOnce.Do(func() {
+		overridesLoad()
+	})
+}
+
+// seriesRoot returns the SH root of an EP or SH programID (SH + 8 digits).
+func seriesRoot(programID string) string {
+	if len(programID) < 10 {
+		return ""
+	}
+	switch strings.ToUpper(programID[:2]) {
+	case "EP", "SH":
+		return "SH" + programID[2:10]
+	}
+	return ""
+}
+
+// parseOverrideRule checks a record and turns it into a rule.
+func parseOverrideRule(rec overrideRecord) (rule overrideRule, err error) {
+
+	match := strings.TrimSpace(rec.Title)
+	rule.ImageID = strings.TrimSpace(rec.ImageID)
+	rule.Station = normalizeStationID(rec.Station)
+
+	if match == "" || rule.ImageID == "" {
+		err = errors.New("empty title or imageID")
+		return
+	}
+
+	// Only the exact lower-case prefixes without a space after the colon
+	// select a matcher, so titles like "Series: The Expanse" stay titles.
+	rule.Kind, rule.Key = overrideByTitle, match
+	if i := strings.Index(match, ":"); i > 0 && !strings.HasPrefix(match[i+1:], " ") {
+		switch kind := match[:i]; kind {
+		case overrideByProgram, overrideBySeries, overrideByTitle, overrideByRegex:
+			rule.Kind, rule.Key = kind, strings.TrimSpace(match[i+1:])
+		}
+	}
+
+	if rule.Key == "" {
+		err = fmt.Errorf("empty %s", rule.Kind)
+		return
+	}
+
+	if err = checkOverrideImage(rule.ImageID); err != nil {
+		return
+	}
+
+	switch rule.Kind {
+	case overrideByProgram:
+		rule.Key = strings.ToUpper(rule.Key)
+		if len(rule.Key) != 14 {
+			err = fmt.Errorf("invalid programID %q: expected 14 characters, e.g. EP012345670001", rule.Key)
+		}
+	case overrideBySeries:
+		root := seriesRoot(strings.ToUpper(rule.Key))
+		if root == "" {
+			err = fmt.Errorf("invalid series ID %q: expected SH01234567 or an EP/SH programID", rule.Key)
+		}
+		rule.Key = root
+	case overrideByTitle:
+		// Titles are matched case-insensitively
+		rule.Key = strings.ToLower(rule.Key)
+	case overrideByRegex:
+		if rule.re, err = regexp.Compile(rule.Key); err != nil {
+			err = fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	return
+}
+
+// parseOverrides reads "match,imageID[,stationID]" records (CSV, one per line).
+func parseOverrides(data []byte) (records []overrideRecord, problems []overrideProblem) {
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		r := csv.NewReader(strings.NewReader(line))
+		r.TrimLeadingSpace = true
+		r.FieldsPerRecord = -1
+		record, err := r.Read()
+		if err != nil {
+			problems = append(problems, overrideProblem{Line: lineNo, Err: err})
+			continue
+		}
+		if len(record) != 2 && len(record) != 3 {
+			problems = append(problems, overrideProblem{Line: lineNo, Err: fmt.Errorf("expected 2 or 3 fields, got %d", len(record))})
+			continue
+		}
+
+		rec := overrideRecord{Title: strings.TrimSpace(record[0]), ImageID: strings.TrimSpace(record[1])}
+		if len(record) == 3 {
+			rec.Station = strings.TrimSpace(record[2])
+		}
+
+		if _, err := parseOverrideRule(rec); err != nil {
+			problems = append(problems, overrideProblem{Line: lineNo, Err: err})
+			continue
+		}
+
+		records = append(records, rec)
+	}
+
+	return
+}
+
+// formatOverrides writes records in the overrides file format.
+func formatOverrides(records []overrideRecord) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, r := range records {
+		if len(r.Station) != 0 {
+			_ = w.Write([]string{r.Title, r.ImageID, r.Station})
+		} else {
+			_ = w.Write([]string{r.Title, r.ImageID})
+		}
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// overridesDiff lists the overrides (see overrideRule.String) a reload added,
+// removed or pointed at another image.
+type overridesDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+
+	// imageIDs that are no longer used by any override
+	dropped []string
+}
+
+func (d overridesDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// overridesLoad (re)reads the overrides file and replaces the loaded overrides.
+func overridesLoad() (diff overridesDiff) {
+	path := overridesFilePath()
+	keyed := map[overrideKey]string{}
+	var regexps []overrideRule
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Overrides: failed to read overrides file", "path", path, "error", err)
+		}
+	} else {
+		records, problems := parseOverrides(data)
+		for _, p := range problems {
+			logger.Warn("Overrides: unable to parse line", "path", path, "line", p.Line, "error", p.Err)
+		}
+		for _, rec := range records {
+			rule, _ := parseOverrideRule(rec)
+			if rule.Kind == overrideByRegex {
+				regexps = append(regexps, rule)
+			} else {
+				keyed[rule.overrideKey] = rule.ImageID
+			}
+		}
+	}
+
+	current := overridesByName(keyed, regexps)
+
+	imageIDs := make(map[string]struct{}, len(keyed)+len(regexps))
+	addImageID := func(imageID string) {
+		imageIDs[imageID] = struct{}{}
+		// Downloaded URL overrides are exempt from the purge too
+		if rawURL, ok := overrideURL(imageID); ok {
+			imageIDs[overrideCacheName(rawURL)] = struct{}{}
+		}
+	}
+	for _, imageID := range keyed {
+		addImageID(imageID)
+	}
+	for _, rule := range regexps {
+		addImageID(rule.ImageID)
+	}
+
+	overridesMu.Lock()
+	previous := overridesByName(overrideKeyed, overrideRegexps)
+	previousImageIDs := overrideImageIDs
+	overridesPath = path
+	overrideKeyed = keyed
+	overrideRegexps = regexps
+	overrideImageIDs = imageIDs
+	overridesEnabled = len(current) > 0
+	overridesMu.Unlock()
+
+	if len(current) > 0 {
+		logger.Info("Overrides: loaded image overrides", "count", len(current), "path", path)
+	}
+
+	for name, imageID := range current {
+		before, ok := previous[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case before != imageID:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	for imageID := range previousImageIDs {
+		if _, ok := imageIDs[imageID]; !ok {
+			diff.dropped = append(diff.dropped, imageID)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return
+}
+
+// overridesByName maps overrideRule.String to the imageID. For duplicate
+// regexes the first one wins, like in overrideMatch.
+func overridesByName(keyed map[overrideKey]string, regexps []overrideRule) map[string]string {
+	names := make(map[string]string, len(keyed)+len(regexps))
+	for key, imageID := range keyed {
+		names[overrideRule{overrideKey: key}.String()] = imageID
+	}
+	for _, rule := range regexps {
+		if _, ok := names[rule.String()]; !ok {
+			names[rule.String()] = rule.ImageID
+		}
+	}
+	return names
+}
+
+// overridesReload makes edits to the overrides file effective without a restart.
+func overridesReload() {
+	overridesOnce.Do(func() {})
+
+	diff := overridesLoad()
+	if diff.empty() {
+		return
+	}
+
+	logger.Info("Overrides: reloaded", "path", overridesFilePath(), "added", diff.Added, "removed", diff.Removed, "changed", diff.Changed)
+
+	// The proxy would keep serving a dropped override image from the index
+	if indexLoaded && len(diff.dropped) > 0 {
+		if err := indexDeleteImageIDs(diff.dropped); err != nil {
+			logger.Warn("Overrides: failed to prune index for dropped override images", "error", err)
+		}
+	}
+}
+
+// overrideMatch returns the override for a programme with the given titles on
+// station (empty for any station), in the precedence described at the top.
+func overrideMatch(programID, station string, titles []string) (rule overrideRule, ok bool) {
+	overridesInit()
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	if !overridesEnabled {
+		return
+	}
+
+	for i := range titles {
+		titles[i] = strings.TrimSpace(titles[i])
+	}
+
+	scopes := []string{""}
+	if station = normalizeStationID(station); len(station) != 0 {
+		scopes = []string{station, ""}
+	}
+
+	for _, scope := range scopes {
+		keys := []overrideKey{
+			{Kind: overrideByProgram, Key: strings.ToUpper(programID), Station: scope},
+			{Kind: overrideBySeries, Key: seriesRoot(programID), Station: scope},
+		}
+		for _, title := range titles {
+			keys = append(keys, overrideKey{Kind: overrideByTitle, Key: strings.ToLower(title), Station: scope})
+		}
+
+		for _, key := range keys {
+			if len(key.Key) == 0 {
+				continue
+			}
+			if imageID, found := overrideKeyed[key]; found {
+				return overrideRule{overrideKey: key, ImageID: imageID}, true
+			}
+		}
+
+		for _, r := range overrideRegexps {
+			if r.Station != scope {
+				continue
+			}
+			for _, title := range titles {
+				if len(title) != 0 && r.re.MatchString(title) {
+					return r, true
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// programTitles returns the Title120 values of a cached programme.
+func programTitles(programID string) (titles []string) {
+	Cache.RLock()
+	defer Cache.RUnlock()
+
+	if p, ok := Cache.Program[programID]; ok {
+		for _, t := range p.Titles {
+			titles = append(titles, t.Title120)
+		}
+	}
+
+	return
+}
+
+func overrideImageForTitle(title string) (string, bool) {
+	rule, ok := overrideMatch("", "", []string{title})
+	return rule.ImageID, ok
+}
+
+// overrideImageForProgram resolves the override for a cached programme on
+// station (empty for any station).
+func overrideImageForProgram(programID, station string) (overrideRule, bool) {
+	overridesInit()
+	overridesMu.RLock()
+	enabled := overridesEnabled
+	overridesMu.RUnlock()
+	if !enabled {
+		return overrideRule{}, false
+	}
+
+	return overrideMatch(programID, station, programTitles(programID))
+}
+
+// overrideImageForProgramOrTitle attempts to resolve an override for a program.
+// Besides the cached program metadata (Title120) it also matches the provided
+// fallbackTitle (e.g. from a schedule entry) so overrides still work when
+// program metadata is missing.
+func overrideImageForProgramOrTitle(programID, station, fallbackTitle string) (overrideRule, bool) {
+	return overrideMatch(programID, station, append(programTitles(programID), fallbackTitle))
+}
+
+func isOverrideImageID(imageID string) bool {
+	overridesInit()
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	if !overridesEnabled || imageID == "" {
+		return false
+	}
+	_, ok := overrideImageIDs[imageID]
+	return ok
+}
+
+var overridesFileMu sync.Mutex
+
+// setOverride writes rec to the overrides file and replaces an override with
+// the same match and station. Other lines are kept as they are.
+func setOverride(rec overrideRecord) (err error) {
+
+	rule, err := parseOverrideRule(rec)
+	if err != nil {
+		return
+	}
+
+	overridesFileMu.Lock()
+	defer overridesFileMu.Unlock()
+
+	path := overridesFilePath()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if records, _ := parseOverrides([]byte(line)); len(records) == 1 {
+			if existing, _ := parseOverrideRule(records[0]); existing.overrideKey == rule.overrideKey {
+				continue
+			}
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	buf.Write(formatOverrides([]overrideRecord{rec}))
+
+	return atomicfile.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOverrideRule(t *testing.T) {
+	tests := []struct {
+		rec     overrideRecord
+		want    overrideKey
+		wantErr bool
+	}{
+		{rec: overrideRecord{Title: "The Simpsons", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "the simpsons"}},
+		{rec: overrideRecord{Title: "Law & Order: Special Victims Unit", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "law & order: special victims unit"}},
+		{rec: overrideRecord{Title: "title:Regex: The Show", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "regex: the show"}},
+		{rec: overrideRecord{Title: "program:ep012345670001", ImageID: "p1", Station: "10001"}, want: overrideKey{Kind: overrideByProgram, Key: "EP012345670001", Station: "10001"}},
+		{rec: overrideRecord{Title: "series:EP012345670001", ImageID: "p1"}, want: overrideKey{Kind: overrideBySeries, Key: "SH01234567"}},
+		{rec: overrideRecord{Title: "Series: The Expanse", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "series: the expanse"}},
+		{rec: overrideRecord{Title: "Program: Kids", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "program: kids"}},
+		{rec: overrideRecord{Title: "series: SH01234567", ImageID: "p1"}, want: overrideKey{Kind: overrideByTitle, Key: "series: sh01234567"}},
+		{rec: overrideRecord{Title: "regex:^Law & Order", ImageID: "p1"}, want: overrideKey{Kind: overrideByRegex, Key: "^Law & Order"}},
+		{rec: overrideRecord{Title: "program:EP0123", ImageID: "p1"}, wantErr: true},
+		{rec: overrideRecord{Title: "series:MV01234567", ImageID: "p1"}, wantErr: true},
+		{rec: overrideRecord{Title: "regex:(", ImageID: "p1"}, wantErr: true},
+		{rec: overrideRecord{Title: "program:", ImageID: "p1"}, wantErr: true},
+		{rec: overrideRecord{Title: "The Simpsons"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		rule, err := parseOverrideRule(tt.rec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOverrideRule(%+v) error = %v, wantErr %v", tt.rec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && rule.overrideKey != tt.want {
+			t.Errorf("parseOverrideRule(%+v) = %+v, want %+v", tt.rec, rule.overrideKey, tt.want)
+		}
+	}
+}
+
+func TestOverrideMatchPrecedence(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Files.Cache = filepath.Join(t.TempDir(), "config_cache.json")
+
+	data := "regex:(?i)^law & order,p_regex\n" +
+		"regex:(?i)special victims,p_regex2\n" +
+		"Law & Order: Special Victims Unit,p_title\n" +
+		"series:SH01234567,p_series\n" +
+		"program:EP012345670001,p_program\n" +
+		"Series: The Expanse,p_legacy\n" +
+		"regex:(?i)^law & order,p_kids_regex,20002\n" +
+		"series:SH01234567,p_kids_series,20002\n"
+	if err := os.WriteFile(overridesFilePath(), []byte(data), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	overridesReload()
+
+	tests := []struct {
+		name        string
+		programID   string
+		station     string
+		title       string
+		want        string
+		wantStation string
+	}{
+		{name: "program beats series", programID: "EP012345670001", title: "Law & Order: Special Victims Unit", want: "p_program"},
+		{name: "series beats title", programID: "EP012345670002", title: "Law & Order: Special Victims Unit", want: "p_series"},
+		{name: "title beats regex", programID: "EP099999990001", title: "law & order: special victims unit", want: "p_title"},
+		{name: "regexes in file order", programID: "EP099999990001", title: "Law & Order: Organized Crime and Special Victims", want: "p_regex"},
+		{name: "second regex", programID: "EP099999990001", title: "Special Victims", want: "p_regex2"},
+		{name: "station series beats program", programID: "EP012345670001", station: "20002", title: "Law & Order", want: "p_kids_series", wantStation: "20002"},
+		{name: "station regex beats title", programID: "EP099999990001", station: "20002.schedulesdirect.org", title: "Law & Order: Special Victims Unit", want: "p_kids_regex", wantStation: "20002"},
+		{name: "other station", programID: "EP012345670002", station: "10001", title: "Law & Order", want: "p_series"},
+		{name: "legacy title with a prefix", programID: "EP077777770001", title: "Series: The Expanse", want: "p_legacy"},
+		{name: "no match", programID: "MV000000010000", station: "20002", title: "Heat", want: ""},
+	}
+
+	for _, tt := range tests {
+		rule, ok := overrideMatch(tt.programID, tt.station, []string{tt.title})
+		if ok != (tt.want != "") || rule.ImageID != tt.want || rule.Station != tt.wantStation {
+			t.Errorf("%s: overrideMatch() = %q@%q, %v; want %q@%q", tt.name, rule.ImageID, rule.Station, ok, tt.want, tt.wantStation)
+		}
+	}
+
+	if !isOverrideImageID("p_kids_regex") {
+		t.Errorf("isOverrideImageID() = false for a station override image")
+	}
+}
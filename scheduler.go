@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const defaultRefreshSchedule = "0 2 * * *"
+
+// refreshMu serializes EPG refreshes so a slow run never overlaps the next tick.
+var refreshMu sync.Mutex
+
+// configReloaded wakes the daemon loop after the config was reloaded, so a
+// changed Refresh Schedule applies without waiting for the next run.
+var configReloaded = make(chan struct{}, 1)
+
+// parseRefreshSchedule parses a standard 5-field cron expression
+// (minute hour day-of-month month day-of-week), the same format that
+// tools/nextrun and the container's CRON_SCHEDULE use, or a descriptor
+// such as @daily or @every 6h.
+func parseRefreshSchedule(expr string) (cron.Schedule, error) {
+	expr = strings.Trim(strings.TrimSpace(expr), "\"")
+	if expr == "" {
+		return nil, fmt.Errorf("refresh schedule is empty")
+	}
+
+	p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	s, err := p.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh schedule %q: %w", expr, err)
+	}
+
+	return s, nil
+}
+
+// runRefresh performs one SD.Update; concurrent callers wait for the running one.
+func runRefresh(filename string) (err error) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	return refresh(filename)
+}
+
+// startRefresh runs a refresh in the background and reports false if one is
+// already running.
+func startRefresh(filename string) bool {
+	if !refreshMu.TryLock() {
+		return false
+	}
+
+	go func() {
+		defer refreshMu.Unlock()
+		_ = refresh(filename)
+	}()
+
+	return true
+}
+
+// refreshResult describes the last finished refresh.
+type refreshResult struct {
+	Started        time.Time
+	Finished       time.Time
+	Err            error
+	AccountExpires time.Time // from the SD status of the last refresh that logged in
+}
+
+var (
+	lastRefreshMu sync.RWMutex
+	lastRefresh   refreshResult
+)
+
+// lastRefreshResult returns the result of the last refresh (zero before the first one).
+func lastRefreshResult() refreshResult {
+	lastRefreshMu.RLock()
+	defer lastRefreshMu.RUnlock()
+	return lastRefresh
+}
+
+// refresh performs one SD.Update; the caller holds refreshMu.
+func refresh(filename string) (err error) {
+	start := time.Now()
+	var sd SD
+	err = sd.Update(filename)
+
+	lastRefreshMu.Lock()
+	lastRefresh.Started = start
+	lastRefresh.Finished = time.Now()
+	lastRefresh.Err = err
+	if expires := sd.Resp.Status.Account.Expires; !expires.IsZero() {
+		lastRefresh.AccountExpires = expires
+	}
+	lastRefreshMu.Unlock()
+
+	if err != nil {
+		logger.Error("EPG refresh failed", "error", err, "duration", time.Since(start))
+		return
+	}
+
+	logger.Info("EPG refresh finished", "duration", time.Since(start))
+	return
+}
+
+// RunDaemon keeps epgo in the foreground: the image proxy (if enabled) keeps
+// serving while SD.Update runs inside the process on the configured
+// Refresh Schedule.
+func RunDaemon(filename string) (err error) {
+
+	// The config for the proxy and the schedule; every refresh reads it again.
+	if _, err = os.Stat(filename); err != nil {
+		return fmt.Errorf("configuration file not found, create it with -configure: %w", err)
+	}
+
+	Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
+	if err = Config.Open(); err != nil {
+		return
+	}
+
+	spec := Config.Options.RefreshSchedule
+	schedule, err := parseRefreshSchedule(spec)
+	if err != nil {
+		return
+	}
+
+	if Config.Server.Enable {
+		imgDir := strings.TrimSpace(Config.Options.Images.Path)
+		if imgDir == "" {
+			imgDir = "images"
+		}
+		port := strings.TrimSpace(Config.Server.Port)
+		if port == "" {
+			port = "8080"
+		}
+
+		logger.Info("Starting image proxy in daemon mode", "address", Config.Server.Address, "port", port, "dir", imgDir)
+		go StartServer(imgDir, port)
+	}
+
+	// Also watch the config file when the proxy is off
+	startFileWatcher()
+
+	// Initial refresh; the proxy serves the cached images meanwhile and a
+	// failure here must not stop it.
+	_ = runRefresh(filename)
+
+	for {
+		next := schedule.Next(time.Now())
+		logger.Info("Next EPG refresh scheduled", "schedule", spec, "at", next.Format(time.RFC1123Z))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			_ = runRefresh(filename)
+		case <-configReloaded:
+			timer.Stop()
+		}
+
+		// Pick up schedule changes made to the config file since the last run.
+		// Reloads and refreshes replace Config meanwhile, so read a copy.
+		latest := currentConfig().Options.RefreshSchedule
+		if s, perr := parseRefreshSchedule(latest); perr == nil {
+			schedule, spec = s, latest
+		} else {
+			logger.Warn("Keeping previous refresh schedule", "error", perr)
+		}
+	}
+}
+
+// refreshRunning reports whether a refresh holds refreshMu right now.
+func refreshRunning() bool {
+	if refreshMu.TryLock() {
+		refreshMu.Unlock()
+		return false
+	}
+	return true
+}
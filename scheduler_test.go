@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRefreshSchedule(t *testing.T) {
+	from := time.Date(2025, 3, 10, 14, 30, 0, 0, time.Local) // Monday
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "daily cron", expr: "0 2 * * *", want: time.Date(2025, 3, 11, 2, 0, 0, 0, time.Local)},
+		{name: "quoted with spaces", expr: ` "0 2 * * *" `, want: time.Date(2025, 3, 11, 2, 0, 0, 0, time.Local)},
+		{name: "every six hours", expr: "0 */6 * * *", want: time.Date(2025, 3, 10, 18, 0, 0, 0, time.Local)},
+		{name: "weekdays list", expr: "15 4 * * 0,6", want: time.Date(2025, 3, 15, 4, 15, 0, 0, time.Local)},
+		{name: "daily descriptor", expr: "@daily", want: time.Date(2025, 3, 11, 0, 0, 0, 0, time.Local)},
+		{name: "interval", expr: "@every 6h", want: from.Add(6 * time.Hour)},
+		{name: "empty", expr: "  ", wantErr: true},
+		{name: "empty quotes", expr: `""`, wantErr: true},
+		{name: "seconds field", expr: "0 0 2 * * *", wantErr: true},
+		{name: "too few fields", expr: "0 2 *", wantErr: true},
+		{name: "out of range", expr: "61 2 * * *", wantErr: true},
+		{name: "text", expr: "every day", wantErr: true},
+		{name: "bad interval", expr: "@every often", wantErr: true},
+		{name: "unknown descriptor", expr: "@fortnightly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseRefreshSchedule(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRefreshSchedule(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("parseRefreshSchedule(%q).Next() = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDaemonInvalidSchedule(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	data := []byte(`Files:
+  Cache: ` + filepath.Join(dir, "config_cache.json") + `
+  XMLTV: ` + filepath.Join(dir, "config.xml") + `
+Options:
+  Refresh Schedule: "every day"
+`)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// The schedule is checked before the proxy and the first refresh start
+	if err := RunDaemon(filename); err == nil {
+		t.Fatal("RunDaemon() error = nil, want invalid refresh schedule")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.xml")); !os.IsNotExist(err) {
+		t.Errorf("XMLTV file written before the schedule was checked: %v", err)
+	}
+
+	if err := RunDaemon(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("RunDaemon() error = nil for a missing config file")
+	}
+}
@@ -45,6 +45,12 @@ func getMsg(code int) (msg string) {
 		msg = "Select Provider"
 	case 0204:
 		msg = "Select Lineup"
+	case 0205:
+		msg = "Satellite (DVB-S)"
+	case 0206:
+		msg = "Postal Code (Cable, IPTV, Antenna)"
+	case 0207:
+		msg = "Antenna Transmitters"
 
 	case 0300:
 		msg = "Update Config File"
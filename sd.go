@@ -26,7 +26,7 @@ func (sd *SD) Init() (err error) {
 		sd.Req.Compression = false
 		sd.Token = ""
 
-		sd.Req.Data, err = json.MarshalIndent(Config.Account, "", "  ")
+		sd.Req.Data, err = json.MarshalIndent(currentConfig().Account, "", "  ")
 		if err != nil {
 			logger.Error("could not marshall request data to get token", "error", err)
 			return err
@@ -70,7 +70,7 @@ func (sd *SD) Init() (err error) {
 
 		logger.Info("", "Expiration", sd.Resp.Status.Account.Expires)
 		logger.Info("", "Lineups", len(sd.Resp.Status.Lineups), "Limit", sd.Resp.Status.Account.MaxLineups)
-		logger.Info("", "Channels", len(Config.Station))
+		logger.Info("", "Channels", len(currentConfig().Station))
 
 		return
 	}
@@ -91,6 +91,38 @@ func (sd *SD) Init() (err error) {
 		return
 	}
 
+	sd.Satellites = func() (err error) {
+
+		sd.Req.URL = sd.BaseURL + "available/dvb-s"
+		sd.Req.Type = "GET"
+		sd.Req.Data = nil
+		sd.Req.Call = "satellites"
+		sd.Req.Compression = false
+
+		err = sd.Connect()
+		if err != nil {
+			return
+		}
+
+		return
+	}
+
+	sd.Transmitters = func() (err error) {
+
+		sd.Req.URL = fmt.Sprintf("%stransmitters%s", sd.BaseURL, sd.Req.Parameter)
+		sd.Req.Type = "GET"
+		sd.Req.Data = nil
+		sd.Req.Call = "transmitters"
+		sd.Req.Compression = false
+
+		err = sd.Connect()
+		if err != nil {
+			return
+		}
+
+		return
+	}
+
 	sd.Headends = func() (err error) {
 
 		sd.Req.URL = fmt.Sprintf("%sheadends%s", sd.BaseURL, sd.Req.Parameter)
@@ -141,6 +173,21 @@ func (sd *SD) Init() (err error) {
 		return
 	}
 
+	sd.ScheduleMD5 = func() (err error) {
+
+		sd.Req.URL = fmt.Sprintf("%sschedules/md5", sd.BaseURL)
+		sd.Req.Type = "POST"
+		sd.Req.Call = "schedulemd5"
+		sd.Req.Compression = false
+
+		err = sd.Connect()
+		if err != nil {
+			return
+		}
+
+		return
+	}
+
 	sd.Program = func() (err error) {
 
 		sd.Req.Type = "POST"
@@ -273,11 +320,26 @@ func (sd *SD) Connect() (err error) {
 		sdStatus.Message = sd.Resp.Status.Message
 
 	case "countries":
+		sd.Resp.Countries = nil
 		err = json.Unmarshal(body, &sd.Resp.Countries)
 		if err != nil {
 			logger.Error("could not unmarshal countries response", "error", err)
 		}
 
+	case "satellites":
+		sd.Resp.Satellites = nil
+		err = json.Unmarshal(body, &sd.Resp.Satellites)
+		if err != nil {
+			logger.Error("could not unmarshal satellites response", "error", err)
+		}
+
+	case "transmitters":
+		sd.Resp.Transmitters = nil
+		err = json.Unmarshal(body, &sd.Resp.Transmitters)
+		if err != nil {
+			logger.Error("could not unmarshal transmitters response", "error", err)
+		}
+
 	case "headends":
 		err = json.Unmarshal(body, &sd.Resp.Headend)
 		if err != nil {
@@ -294,7 +356,7 @@ func (sd *SD) Connect() (err error) {
 		sdStatus.Code = sd.Resp.Lineup.Code
 		sdStatus.Message = sd.Resp.Lineup.Message
 
-	case "schedule", "program":
+	case "schedule", "schedulemd5", "program":
 		sd.Resp.Body = body
 
 	}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"epgo/internal/atomicfile"
 )
 
 // Global token state shared across the process.
@@ -30,7 +32,7 @@ type persistedToken struct {
 
 func tokenFilePath() string {
 	// Persist token next to the cache file as a sidecar JSON.
-	p := Config.Files.Cache
+	p := currentConfig().Files.Cache
 	if p == "" {
 		// default inside container
 		return "/app/config_cache.sdtoken.json"
@@ -75,7 +77,12 @@ func saveTokenToDisk(tok string, exp time.Time) {
 		Token:       tok,
 		TokenExpiry: exp.UTC(),
 	}, "", "  ")
-	_ = os.WriteFile(path, blob, 0644)
+	if err := atomicfile.WriteFile(path, blob, 0644); err != nil {
+		if logger != nil {
+			logger.Warn("SD token: unable to save to disk", "path", path, "error", err)
+		}
+		return
+	}
 	if logger != nil {
 		logger.Info("SD token: saved to disk", "expires_utc", exp.UTC())
 	}
@@ -204,6 +211,7 @@ func getSDTokenWithOptions(skipDiskLoad bool) (string, error) {
 		if logger != nil {
 			logger.Error("SD token: LOGIN failed", "error", err)
 		}
+		recordTokenRefresh(false)
 		return "", err
 	}
 
@@ -216,6 +224,7 @@ func getSDTokenWithOptions(skipDiskLoad bool) (string, error) {
 	sdTokenExpiry = newExp
 	sdTokenMu.Unlock()
 	saveTokenToDisk(newToken, newExp)
+	recordTokenRefresh(true)
 
 	if logger != nil {
 		logger.Info("SD token: LOGIN succeeded", "expires_utc", newExp)
@@ -230,6 +239,7 @@ func forceRefreshToken() (string, error) {
 	if logger != nil {
 		logger.Warn("SD token: forced refresh requested (clearing token)")
 	}
+	metricForcedTokenRefreshes.Add(1)
 	sdTokenMu.Lock()
 	sdToken = ""
 	sdTokenExpiry = time.Time{}
@@ -7,10 +7,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"epgo/internal/atomicfile"
 )
 
 var (
@@ -55,7 +58,9 @@ func endImageFetch(imageID string, outcome imageFetchOutcome) {
 	}
 }
 
-func fetchAndCacheSDImage(programID, imageID, filePath string) *imageFetchError {
+func fetchAndCacheSDImage(programID, imageID, filePath string) (fetchErr *imageFetchError) {
+	defer func() { recordImageDownload(fetchErr == nil) }()
+
 	// Token (only when a download is required)
 	token, err := getSDToken()
 	if err != nil {
@@ -160,7 +165,7 @@ func fetchAndCacheSDImage(programID, imageID, filePath string) *imageFetchError
 		}
 
 		// Save to disk
-		if err := os.WriteFile(filePath, body, 0644); err != nil {
+		if err := atomicfile.WriteFile(filePath, body, 0644); err != nil {
 			logger.Error("Proxy: save failed (write)", "programID", programID, "imageID", imageID, "path", filePath, "error", err)
 			return &imageFetchError{status: http.StatusInternalServerError, message: "save failed"}
 		}
@@ -171,7 +176,7 @@ func fetchAndCacheSDImage(programID, imageID, filePath string) *imageFetchError
 }
 
 func ensureProgramMetadata(programID string) bool {
-	if _, ok := Cache.Metadata[programID]; ok {
+	if Cache.hasMetadata(programID) {
 		return true
 	}
 
@@ -225,7 +230,7 @@ func ensureProgramMetadata(programID string) bool {
 		logger.Warn("Proxy: cache save after metadata fetch failed", "programID", programID, "error", err)
 	}
 
-	if _, ok := Cache.Metadata[programID]; ok {
+	if Cache.hasMetadata(programID) {
 		logger.Info("Proxy: metadata stored", "programID", programID)
 		return true
 	}
@@ -258,12 +263,14 @@ func sdImageIDFromURI(uri string) string {
 // - Then prefer higher-ranked categories (e.g., Banner-L1 over Banner-L2)
 // - Ties by width
 func lookupImageMeta(programID, imageID string) (category, aspect string, width, height int, ok bool) {
+	Cache.RLock()
 	m, ok := Cache.Metadata[programID]
+	Cache.RUnlock()
 	if !ok {
 		return "", "", 0, 0, false
 	}
 
-	desired := strings.TrimSpace(Config.Options.Images.PosterAspect)
+	desired := strings.TrimSpace(currentConfig().Options.Images.PosterAspect)
 
 	bestScore := 1 << 30
 	bestWidth := -1
@@ -328,25 +335,34 @@ func sdErrorTime(buf []byte) time.Time {
 
 // StartServer starts a local HTTP server: static files + SD image proxy (pinned + legacy).
 func StartServer(dir string, port string) {
-	// Ensure cached programme metadata is available even if the last EPG refresh failed
-	if err := Cache.Open(); err != nil {
-		logger.Warn("Proxy: unable to open cache; override resolution may be limited", "error", err)
+	// Ensure cached programme metadata is available even if the last EPG refresh failed.
+	// A running refresh (daemon mode) loads the cache itself.
+	if refreshMu.TryLock() {
+		if err := Cache.Open(); err != nil {
+			logger.Warn("Proxy: unable to open cache; override resolution may be limited", "error", err)
+		}
+		refreshMu.Unlock()
 	}
 
 	// Load ProgramID → imageID index
 	indexInit()
 
-	if Config.Options.Images.ProxyMode && !Config.Options.Images.PreindexSDPosters {
+	// Pick up edits to overrides.txt and the config file
+	startFileWatcher()
+
+	cfg := currentConfig()
+
+	if cfg.Options.Images.ProxyMode && !cfg.Options.Images.PreindexSDPosters {
 		logger.Info("Proxy: SD poster index will be built during runtime")
 	}
 
-	cacheDays := Config.Options.Images.MaxCacheAgeDays
-	folderImage := Config.Options.Images.Path
+	cacheDays := cfg.Options.Images.MaxCacheAgeDays
+	folderImage := cfg.Options.Images.Path
 	if folderImage == "" {
 		folderImage = "images"
 	}
 
-	if Config.Options.Images.PurgeStale && cacheDays > 0 {
+	if cfg.Options.Images.PurgeStale && cacheDays > 0 {
 		if removed, err := purgeStalePosterFiles(folderImage, cacheDays); err != nil {
 			logger.Warn("Proxy: purge of stale cached posters failed", "path", folderImage, "error", err)
 		} else if removed > 0 {
@@ -360,446 +376,540 @@ func StartServer(dir string, port string) {
 		logger.Info("Proxy: configured for indefinite cache age", "max_cache_days", cacheDays)
 	}
 
+	mux := newServerMux(&cfg, dir, port)
+
+	logger.Info("Starting server", "address", "http://"+cfg.Server.Address+":"+port, "serving", filepath.Clean(dir))
+	srv := &http.Server{Addr: ":" + port, Handler: mux}
+	if err := serveUntilShutdown(srv); err != nil {
+		logger.Error("Server failed to start", "error", err)
+	}
+}
+
+// newServerMux registers the routes of the HTTP server.
+func newServerMux(cfg *config, dir, port string) *http.ServeMux {
+
 	mux := http.NewServeMux()
 
 	// /proxy/sd/{programID}[/<imageID>]
-	mux.HandleFunc("/proxy/sd/", func(w http.ResponseWriter, r *http.Request) {
-		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/proxy/sd/"), "/")
-		if len(parts) == 0 || parts[0] == "" {
-			http.Error(w, "missing programID", http.StatusBadRequest)
-			return
+	mux.Handle("/proxy/sd/", instrumentProxy(proxySDHandler))
+
+	// XMLTV files of all outputs and their compressed copies (the static root is usually the image folder)
+	registered := make(map[string]bool)
+	for _, profile := range cfg.xmltvProfiles() {
+		xmltv := strings.TrimSpace(profile.XMLTV)
+		if xmltv == "" {
+			continue
 		}
-		programID := strings.TrimSuffix(parts[0], ".jpg")
-		imageID := ""
-		if len(parts) >= 2 && parts[1] != "" {
-			imageID = strings.TrimSuffix(parts[1], ".jpg")
+		for _, suffix := range []string{"", ".gz", ".xz"} {
+			filePath := xmltv + suffix
+			route := "/" + filepath.Base(filePath)
+			if registered[route] {
+				logger.Warn("XMLTV file name already served; skipping", "file", filePath, "route", route)
+				continue
+			}
+			registered[route] = true
+			mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+				serveXMLTVFile(w, r, filePath)
+			})
 		}
+	}
 
-		if overrideID, ok := overrideImageForProgram(programID); ok {
-			imageID = overrideID
+	// Admin UI
+	if cfg.Server.Admin.Enable {
+		if len(cfg.Server.Admin.Password) == 0 {
+			logger.Warn("Admin UI is enabled but has no password; not starting it")
+		} else {
+			mux.Handle("/admin/", adminHandler())
+			logger.Info("Admin UI enabled", "address", "http://"+cfg.Server.Address+":"+port+"/admin/")
 		}
+	}
 
-		if imageID != "" && !isSDImageID(imageID) {
-			logger.Warn("Proxy: non-SD image request rejected", "programID", programID, "imageID", imageID)
-			http.NotFound(w, r)
-			return
-		}
+	// Prometheus metrics
+	mux.HandleFunc("/metrics", metricsHandler)
 
-		blockGlobal, blockRemain := shouldBlockGlobal()
+	// Health and readiness probes
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
 
-		// Ensure image folder exists
-		folderImage := Config.Options.Images.Path
-		if folderImage == "" {
-			folderImage = "images"
-		}
-		if err := os.MkdirAll(folderImage, 0755); err != nil {
-			http.Error(w, "failed to prepare image folder", http.StatusInternalServerError)
-			return
-		}
+	// REST API; apiAuth checks the token on every request, so a token set
+	// later by a config reload enables it
+	mux.Handle("/api/", apiHandler())
+	if len(cfg.Server.APIToken) != 0 {
+		logger.Info("API enabled", "address", "http://"+cfg.Server.Address+":"+port+"/api/")
+	}
 
-		// --- PINNED MODE: /proxy/sd/{programID}/{imageID} ---
-		if imageID != "" {
-			filePath := filepath.Join(folderImage, imageID+".jpg")
-
-			logWithMeta := func(prefix string, allowMetaFetch bool) {
-				cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID)
-				if ok {
-					logger.Info(prefix,
-						"programID", programID, "imageID", imageID,
-						"category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
-				} else {
-					if allowMetaFetch && ensureProgramMetadata(programID) {
-						if cat2, asp2, w2, h2, ok2 := lookupImageMeta(programID, imageID); ok2 {
-							logger.Info(prefix,
-								"programID", programID, "imageID", imageID,
-								"category", cat2, "aspect", asp2, "w", w2, "h", h2, "path", filePath)
-							return
-						}
-					}
-					logger.Info(prefix+" (no meta)",
-						"programID", programID, "imageID", imageID, "path", filePath)
-				}
-			}
+	// Static server
+	mux.Handle("/", staticHandler(dir))
 
-			// 1) Serve from disk if present
-			if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
-				logWithMeta("Proxy: serve pinned from cache", !blockGlobal)
-				_ = indexSet(programID, imageID)
-				serveFileCached(w, r, filePath)
-				return
-			}
+	return mux
+}
 
-			if blockGlobal {
-				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
-				http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
-				logger.Warn("Proxy: global pause in effect; denying image download", "programID", programID, "remaining", blockRemain)
-				return
-			}
+// proxySDHandler serves /proxy/sd/{programID}[/<imageID>].
+func proxySDHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/proxy/sd/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		http.Error(w, "missing programID", http.StatusBadRequest)
+		return
+	}
+	programID := strings.TrimSuffix(parts[0], ".jpg")
+	imageID := ""
+	if len(parts) >= 2 && parts[1] != "" {
+		imageID = strings.TrimSuffix(parts[1], ".jpg")
+	}
 
-			// 2) Download pinned asset directly (no resolver)
-			token, err := getSDToken()
-			if err != nil {
-				logger.Error("Proxy: token error before pinned fetch", "programID", programID, "imageID", imageID, "error", err)
-				http.Error(w, "token error", http.StatusBadGateway)
-				return
-			}
-			imageURL := fmt.Sprintf("https://json.schedulesdirect.org/20141201/image/%s.jpg?token=%s", imageID, token)
-			logger.Info("Proxy: downloading pinned image", "programID", programID, "imageID", imageID, "url", imageURL)
-
-			client := &http.Client{Timeout: 20 * time.Second}
-			fetch := func(url string) (*http.Response, error) {
-				req, _ := http.NewRequest("GET", url, nil)
-				req.Header.Set("User-Agent", userAgent())
-				return client.Do(req)
-			}
+	// ?station= selects the overrides of one station; their images stay
+	// out of the index, which is shared by all stations. So do the images
+	// pinned by outputs with their own Poster Aspect (see isChosenSDImage).
+	indexPinned := true
+	overridden := false
+	customImage := false
+	if override, ok := overrideImageForProgram(programID, r.URL.Query().Get("station")); ok {
+		imageID = override.ImageID
+		overridden = true
+		indexPinned = len(override.Station) == 0
+		customImage = !isSDImageID(imageID)
+	}
 
-			resp, err := fetch(imageURL)
-			if err != nil {
-				logger.Error("Proxy: pinned fetch failed", "programID", programID, "imageID", imageID, "error", err)
-				http.Error(w, "fetch failed", http.StatusBadGateway)
-				return
-			}
-			if resp.StatusCode == http.StatusUnauthorized {
-				logger.Warn("Proxy: SD token unauthorized for pinned fetch, refreshing", "programID", programID, "imageID", imageID)
-				resp.Body.Close()
-				if token2, err2 := forceRefreshToken(); err2 == nil {
-					imageURL = fmt.Sprintf("https://json.schedulesdirect.org/20141201/image/%s.jpg?token=%s", imageID, token2)
-					resp, err = fetch(imageURL)
-					if err != nil {
-						logger.Error("Proxy: pinned fetch retry failed", "programID", programID, "imageID", imageID, "error", err)
-						http.Error(w, "fetch retry failed", http.StatusBadGateway)
-						return
-					}
-				} else {
-					logger.Error("Proxy: token refresh failed (pinned)", "programID", programID, "imageID", imageID, "error", err2)
-					http.Error(w, "token refresh failed", http.StatusBadGateway)
-					return
-				}
-			}
-			defer resp.Body.Close()
+	if imageID != "" && !isSDImageID(imageID) && !customImage {
+		logger.Warn("Proxy: non-SD image request rejected", "programID", programID, "imageID", imageID)
+		http.NotFound(w, r)
+		return
+	}
 
-			buf, rerr := io.ReadAll(resp.Body)
-			if rerr != nil {
-				logger.Error("Proxy: read pinned body failed", "programID", programID, "imageID", imageID, "error", rerr)
-				http.Error(w, "read failed", http.StatusBadGateway)
-				return
-			}
-			if resp.StatusCode != http.StatusOK {
-				logger.Warn("Proxy: SD returned non-200 for pinned", "programID", programID, "imageID", imageID, "status", resp.Status, "body", truncate(string(buf), 256))
-				http.Error(w, resp.Status, resp.StatusCode)
-				return
-			}
+	blockGlobal, blockRemain := shouldBlockGlobal()
 
-			// Validate payload is an image
-			ct := resp.Header.Get("Content-Type")
-			if ct == "" {
-				ct = http.DetectContentType(buf)
-			}
-			isImage := strings.HasPrefix(strings.ToLower(ct), "image/") && looksLikeImage(buf)
-			if !isImage {
-				bodyText := string(buf)
-				if strings.Contains(bodyText, "Counter resets at 00:00Z.") {
-					ref := sdErrorTime(buf)
-					if ref.IsZero() {
-						ref = time.Now().UTC()
+	// Ensure image folder exists
+	images := currentConfig().Options.Images
+	folderImage := images.Path
+	if folderImage == "" {
+		folderImage = "images"
+	}
+	if err := os.MkdirAll(folderImage, 0755); err != nil {
+		http.Error(w, "failed to prepare image folder", http.StatusInternalServerError)
+		return
+	}
+
+	// Overrides with a file: name or a URL instead of SD art
+	if customImage {
+		serveOverrideImage(w, r, folderImage, programID, imageID)
+		return
+	}
+
+	// --- PINNED MODE: /proxy/sd/{programID}/{imageID} ---
+	if imageID != "" {
+		filePath := filepath.Join(folderImage, imageID+".jpg")
+
+		logWithMeta := func(prefix string, allowMetaFetch bool) {
+			cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID)
+			if ok {
+				logger.Info(prefix,
+					"programID", programID, "imageID", imageID,
+					"category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
+			} else {
+				if allowMetaFetch && ensureProgramMetadata(programID) {
+					if cat2, asp2, w2, h2, ok2 := lookupImageMeta(programID, imageID); ok2 {
+						logger.Info(prefix,
+							"programID", programID, "imageID", imageID,
+							"category", cat2, "aspect", asp2, "w", w2, "h", h2, "path", filePath)
+						return
 					}
-					until := nextUTCMidnightPlus(ref, 5)
-					setGlobalPauseUntil(until, "SD quota message: Counter resets at 00:00Z.")
-					retryAfter := time.Until(until)
-					logger.Warn("Proxy: SD quota message during pinned fetch; pausing",
-						"programID", programID, "imageID", imageID, "retry_after", retryAfter.String(), "until_utc", until, "body", truncate(bodyText, 256))
-					w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
-					http.Error(w, "image downloads paused until next UTC midnight window", http.StatusTooManyRequests)
-					return
 				}
-				logger.Warn("Proxy: SD returned non-image payload for pinned; not caching",
-					"programID", programID, "imageID", imageID, "content_type", ct, "body", truncate(bodyText, 256))
-				http.Error(w, "Schedules Direct returned a non-image payload", http.StatusBadGateway)
-				return
+				logger.Info(prefix+" (no meta)",
+					"programID", programID, "imageID", imageID, "path", filePath)
 			}
+		}
 
-			filePath = filepath.Join(folderImage, imageID+".jpg")
-			if err := os.WriteFile(filePath, buf, 0644); err != nil {
-				logger.Error("Proxy: save failed (pinned write)", "programID", programID, "imageID", imageID, "path", filePath, "error", err)
-				http.Error(w, "save failed", http.StatusInternalServerError)
-				return
+		// 1) Serve from disk if present
+		if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
+			logWithMeta("Proxy: serve pinned from cache", !blockGlobal)
+			if indexPinned && (overridden || isChosenSDImage(programID, imageID)) {
+				_ = indexSet(programID, imageID)
 			}
-			_ = indexSet(programID, imageID)
-			// Always report category (fetch metadata if missing)
-			logWithMeta("Proxy: serve freshly cached (pinned)", true)
+			markProxyOutcome(w, "cache_hit")
 			serveFileCached(w, r, filePath)
 			return
 		}
 
-		// --- LEGACY MODE: /proxy/sd/{programID} (resolver path) ---
-		maxAge := time.Duration(0)
-		if days := Config.Options.Images.MaxCacheAgeDays; days > 0 {
-			maxAge = time.Duration(days) * 24 * time.Hour
-		}
-		purgeEnabled := Config.Options.Images.PurgeStale && maxAge > 0
-		purgeThreshold := maxAge * 2
-		purgeAfterDays := Config.Options.Images.MaxCacheAgeDays * 2
-		now := time.Now()
-
-		indexImageID := ""
-		indexImagePath := ""
-		indexImageExpired := false
-
-		// 1) Try ProgramID → imageID index
-		if entry, ok := indexGetEntry(programID); ok && entry.ImageID != "" {
-			imgID := entry.ImageID
-			indexImageID = imgID
-			indexImagePath = filepath.Join(folderImage, imgID+".jpg")
-			if fi, err := os.Stat(indexImagePath); err == nil && !fi.IsDir() {
-				lastTouch := entry.lastRequest()
-				if lastTouch.IsZero() {
-					lastTouch = fi.ModTime()
-				}
-				purged := false
-				if purgeEnabled && now.Sub(lastTouch) > purgeThreshold {
-					logger.Info("Proxy: purging stale cached image (index hit)",
-						"programID", programID, "imageID", imgID, "path", indexImagePath,
-						"last_request_utc", lastTouch.UTC(), "purge_after_days", purgeAfterDays)
-					if err := os.Remove(indexImagePath); err != nil {
-						logger.Warn("Proxy: failed to remove stale cached image",
-							"programID", programID, "imageID", imgID, "path", indexImagePath, "error", err)
-					} else {
-						if err := indexDeleteImageIDs([]string{imgID}); err != nil {
-							logger.Warn("Proxy: failed to prune index for stale cached image",
-								"imageID", imgID, "error", err)
-						}
-					}
-					indexImageExpired = true
-					purged = true
-					indexImageID = ""
-					indexImagePath = ""
-				}
-				if !purged {
-					expired := false
-					if maxAge > 0 && now.Sub(lastTouch) > maxAge {
-						expired = true
-						indexImageExpired = true
-					}
-					if !expired {
-						if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
-							logger.Info("Proxy: serve from cache (index hit)",
-								"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath)
-						} else {
-							logger.Info("Proxy: serve from cache (index hit, no meta)",
-								"programID", programID, "imageID", imgID, "path", indexImagePath)
-						}
-						_ = indexSet(programID, imgID)
-						serveFileCached(w, r, indexImagePath)
-						return
-					}
-
-					// Log expiration before refreshing
-					if blockGlobal {
-						if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
-							logger.Info("Proxy: serve expired cache during global pause",
-								"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath, "max_cache_days", Config.Options.Images.MaxCacheAgeDays)
-						} else {
-							logger.Info("Proxy: serve expired cache during global pause",
-								"programID", programID, "imageID", imgID, "path", indexImagePath, "max_cache_days", Config.Options.Images.MaxCacheAgeDays)
-						}
-						_ = indexSet(programID, imgID)
-						serveFileCached(w, r, indexImagePath)
-						return
-					}
-					if _, _, _, _, ok := lookupImageMeta(programID, imgID); !ok && !blockGlobal {
-						_ = ensureProgramMetadata(programID)
-					}
-					if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
-						logger.Info("Proxy: cached image expired; refreshing",
-							"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath, "max_cache_days", Config.Options.Images.MaxCacheAgeDays)
-					} else {
-						logger.Info("Proxy: cached image expired; refreshing",
-							"programID", programID, "imageID", imgID, "path", indexImagePath, "max_cache_days", Config.Options.Images.MaxCacheAgeDays)
-					}
-				}
-			} else {
-				indexImageExpired = true
-				logger.Warn("Proxy: index stale, removing mapping", "programID", programID, "imageID", imgID)
-				_ = indexDelete(programID)
-			}
+		if blockGlobal {
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
+			http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
+			logger.Warn("Proxy: global pause in effect; denying image download", "programID", programID, "remaining", blockRemain)
+			return
 		}
 
-		// 2) Resolve via metadata (or fetch-on-miss)
-		chosen, ok := Cache.resolveSDImageForProgram(programID)
-		if !ok || chosen.URI == "" {
-			if !blockGlobal && ensureProgramMetadata(programID) {
-				if ch2, ok2 := Cache.resolveSDImageForProgram(programID); ok2 && ch2.URI != "" {
-					chosen = ch2
-					ok = true
-				}
-			}
+		// 2) Download pinned asset directly (no resolver)
+		token, err := getSDToken()
+		if err != nil {
+			logger.Error("Proxy: token error before pinned fetch", "programID", programID, "imageID", imageID, "error", err)
+			http.Error(w, "token error", http.StatusBadGateway)
+			return
+		}
+		imageURL := fmt.Sprintf("https://json.schedulesdirect.org/20141201/image/%s.jpg?token=%s", imageID, token)
+		logger.Info("Proxy: downloading pinned image", "programID", programID, "imageID", imageID, "url", imageURL)
+		pinnedSaved := false
+		defer func() { recordImageDownload(pinnedSaved) }()
+
+		client := &http.Client{Timeout: 20 * time.Second}
+		fetch := func(url string) (*http.Response, error) {
+			req, _ := http.NewRequest("GET", url, nil)
+			req.Header.Set("User-Agent", userAgent())
+			return client.Do(req)
 		}
 
-		useResolved := ok && chosen.URI != ""
-		if useResolved {
-			candidateID := sdImageIDFromURI(chosen.URI)
-			if !isSDImageID(candidateID) {
-				logger.Warn("Proxy: resolved non-SD image skipped", "programID", programID, "imageID", candidateID, "uri", chosen.URI)
-				useResolved = false
-				imageID = ""
-			} else {
-				logger.Info("Proxy: resolved image candidate",
-					"programID", programID,
-					"imageID", candidateID,
-					"category", chosen.Category, "aspect", chosen.Aspect, "w", chosen.Width, "h", chosen.Height,
-					"uri", chosen.URI)
-				imageID = candidateID
-			}
+		resp, err := fetch(imageURL)
+		if err != nil {
+			logger.Error("Proxy: pinned fetch failed", "programID", programID, "imageID", imageID, "error", err)
+			http.Error(w, "fetch failed", http.StatusBadGateway)
+			return
 		}
-		if !useResolved {
-			imageID = indexImageID
-			if imageID == "" {
-				if blockGlobal {
-					w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
-					http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
-					logger.Warn("Proxy: global pause in effect; no cached image available", "programID", programID, "remaining", blockRemain)
-				} else {
-					logger.Warn("Proxy: no suitable image in metadata", "programID", programID)
-					http.NotFound(w, r)
+		if resp.StatusCode == http.StatusUnauthorized {
+			logger.Warn("Proxy: SD token unauthorized for pinned fetch, refreshing", "programID", programID, "imageID", imageID)
+			resp.Body.Close()
+			if token2, err2 := forceRefreshToken(); err2 == nil {
+				imageURL = fmt.Sprintf("https://json.schedulesdirect.org/20141201/image/%s.jpg?token=%s", imageID, token2)
+				resp, err = fetch(imageURL)
+				if err != nil {
+					logger.Error("Proxy: pinned fetch retry failed", "programID", programID, "imageID", imageID, "error", err)
+					http.Error(w, "fetch retry failed", http.StatusBadGateway)
+					return
 				}
+			} else {
+				logger.Error("Proxy: token refresh failed (pinned)", "programID", programID, "imageID", imageID, "error", err2)
+				http.Error(w, "token refresh failed", http.StatusBadGateway)
 				return
 			}
-			logger.Info("Proxy: using cached image id (no new metadata)", "programID", programID, "imageID", imageID)
 		}
+		defer resp.Body.Close()
 
-		if imageID != "" && !isSDImageID(imageID) {
-			logger.Warn("Proxy: non-SD image mapping ignored", "programID", programID, "imageID", imageID)
-			http.NotFound(w, r)
+		buf, rerr := io.ReadAll(resp.Body)
+		if rerr != nil {
+			logger.Error("Proxy: read pinned body failed", "programID", programID, "imageID", imageID, "error", rerr)
+			http.Error(w, "read failed", http.StatusBadGateway)
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			logger.Warn("Proxy: SD returned non-200 for pinned", "programID", programID, "imageID", imageID, "status", resp.Status, "body", truncate(string(buf), 256))
+			http.Error(w, resp.Status, resp.StatusCode)
 			return
 		}
 
-		// During a global pause, still serve from cache if the resolved image is already on disk
-		// even when the programme→image index lacks an entry.
-		if blockGlobal && imageID != "" {
-			filePath := filepath.Join(folderImage, imageID+".jpg")
-			if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
-				lastTouch := indexLastRequestForImage(imageID)
-				if lastTouch.IsZero() {
-					lastTouch = fi.ModTime()
-				}
-				if maxAge > 0 && now.Sub(lastTouch) > maxAge {
-					logger.Info("Proxy: serve expired cache during global pause (resolved)",
-						"programID", programID, "imageID", imageID, "path", filePath,
-						"max_cache_days", Config.Options.Images.MaxCacheAgeDays)
-				} else {
-					logger.Info("Proxy: serve from cache during global pause (resolved)",
-						"programID", programID, "imageID", imageID, "path", filePath)
+		// Validate payload is an image
+		ct := resp.Header.Get("Content-Type")
+		if ct == "" {
+			ct = http.DetectContentType(buf)
+		}
+		isImage := strings.HasPrefix(strings.ToLower(ct), "image/") && looksLikeImage(buf)
+		if !isImage {
+			bodyText := string(buf)
+			if strings.Contains(bodyText, "Counter resets at 00:00Z.") {
+				ref := sdErrorTime(buf)
+				if ref.IsZero() {
+					ref = time.Now().UTC()
 				}
-				_ = indexSet(programID, imageID)
-				serveFileCached(w, r, filePath)
+				until := nextUTCMidnightPlus(ref, 5)
+				setGlobalPauseUntil(until, "SD quota message: Counter resets at 00:00Z.")
+				retryAfter := time.Until(until)
+				logger.Warn("Proxy: SD quota message during pinned fetch; pausing",
+					"programID", programID, "imageID", imageID, "retry_after", retryAfter.String(), "until_utc", until, "body", truncate(bodyText, 256))
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+				http.Error(w, "image downloads paused until next UTC midnight window", http.StatusTooManyRequests)
 				return
 			}
+			logger.Warn("Proxy: SD returned non-image payload for pinned; not caching",
+				"programID", programID, "imageID", imageID, "content_type", ct, "body", truncate(bodyText, 256))
+			http.Error(w, "Schedules Direct returned a non-image payload", http.StatusBadGateway)
+			return
 		}
 
-		if blockGlobal {
-			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
-			http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
-			logger.Warn("Proxy: global pause in effect; denying image download", "programID", programID, "remaining", blockRemain)
+		filePath = filepath.Join(folderImage, imageID+".jpg")
+		if err := atomicfile.WriteFile(filePath, buf, 0644); err != nil {
+			logger.Error("Proxy: save failed (pinned write)", "programID", programID, "imageID", imageID, "path", filePath, "error", err)
+			http.Error(w, "save failed", http.StatusInternalServerError)
 			return
 		}
+		pinnedSaved = true
+		if indexPinned && (overridden || isChosenSDImage(programID, imageID)) {
+			_ = indexSet(programID, imageID)
+		}
+		// Always report category (fetch metadata if missing)
+		logWithMeta("Proxy: serve freshly cached (pinned)", true)
+		markProxyOutcome(w, "download")
+		serveFileCached(w, r, filePath)
+		return
+	}
 
-		// 3) Serve from disk if present (and update index) provided it hasn't expired
-		filePath := filepath.Join(folderImage, imageID+".jpg")
-		if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
-			lastTouch := indexLastRequestForImage(imageID)
+	// --- LEGACY MODE: /proxy/sd/{programID} (resolver path) ---
+	maxAge := time.Duration(0)
+	if days := images.MaxCacheAgeDays; days > 0 {
+		maxAge = time.Duration(days) * 24 * time.Hour
+	}
+	purgeEnabled := images.PurgeStale && maxAge > 0
+	purgeThreshold := maxAge * 2
+	purgeAfterDays := images.MaxCacheAgeDays * 2
+	now := time.Now()
+
+	indexImageID := ""
+	indexImagePath := ""
+	indexImageExpired := false
+
+	// 1) Try ProgramID → imageID index
+	if entry, ok := indexGetEntry(programID); ok && entry.ImageID != "" {
+		imgID := entry.ImageID
+		indexImageID = imgID
+		indexImagePath = filepath.Join(folderImage, imgID+".jpg")
+		if fi, err := os.Stat(indexImagePath); err == nil && !fi.IsDir() {
+			lastTouch := entry.lastRequest()
 			if lastTouch.IsZero() {
 				lastTouch = fi.ModTime()
 			}
 			purged := false
 			if purgeEnabled && now.Sub(lastTouch) > purgeThreshold {
-				logger.Info("Proxy: purging stale cached image",
-					"programID", programID, "imageID", imageID, "path", filePath,
+				logger.Info("Proxy: purging stale cached image (index hit)",
+					"programID", programID, "imageID", imgID, "path", indexImagePath,
 					"last_request_utc", lastTouch.UTC(), "purge_after_days", purgeAfterDays)
-				if err := os.Remove(filePath); err != nil {
+				if err := os.Remove(indexImagePath); err != nil {
 					logger.Warn("Proxy: failed to remove stale cached image",
-						"programID", programID, "imageID", imageID, "path", filePath, "error", err)
+						"programID", programID, "imageID", imgID, "path", indexImagePath, "error", err)
 				} else {
-					if err := indexDeleteImageIDs([]string{imageID}); err != nil {
-						logger.Warn("Proxy: failed to prune index for stale cached image", "imageID", imageID, "error", err)
+					if err := indexDeleteImageIDs([]string{imgID}); err != nil {
+						logger.Warn("Proxy: failed to prune index for stale cached image",
+							"imageID", imgID, "error", err)
 					}
 				}
+				indexImageExpired = true
 				purged = true
+				indexImageID = ""
+				indexImagePath = ""
 			}
 			if !purged {
 				expired := false
 				if maxAge > 0 && now.Sub(lastTouch) > maxAge {
 					expired = true
+					indexImageExpired = true
 				}
 				if !expired {
-					if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID); ok {
-						logger.Info("Proxy: serve from cache (by imageID)",
-							"programID", programID, "imageID", imageID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
+					if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
+						logger.Info("Proxy: serve from cache (index hit)",
+							"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath)
+					} else {
+						logger.Info("Proxy: serve from cache (index hit, no meta)",
+							"programID", programID, "imageID", imgID, "path", indexImagePath)
+					}
+					_ = indexSet(programID, imgID)
+					markProxyOutcome(w, "index_hit")
+					serveFileCached(w, r, indexImagePath)
+					return
+				}
+
+				// Log expiration before refreshing
+				if blockGlobal {
+					if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
+						logger.Info("Proxy: serve expired cache during global pause",
+							"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath, "max_cache_days", images.MaxCacheAgeDays)
 					} else {
-						logger.Info("Proxy: serve from cache (by imageID, no meta)",
-							"programID", programID, "imageID", imageID, "path", filePath)
+						logger.Info("Proxy: serve expired cache during global pause",
+							"programID", programID, "imageID", imgID, "path", indexImagePath, "max_cache_days", images.MaxCacheAgeDays)
 					}
-					_ = indexSet(programID, imageID)
-					serveFileCached(w, r, filePath)
+					_ = indexSet(programID, imgID)
+					markProxyOutcome(w, "index_hit")
+					serveFileCached(w, r, indexImagePath)
 					return
 				}
-				if !(indexImageExpired && indexImageID == imageID) {
-					logger.Info("Proxy: cached candidate expired; refreshing", "programID", programID, "imageID", imageID, "path", filePath)
+				if _, _, _, _, ok := lookupImageMeta(programID, imgID); !ok && !blockGlobal {
+					_ = ensureProgramMetadata(programID)
+				}
+				if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imgID); ok {
+					logger.Info("Proxy: cached image expired; refreshing",
+						"programID", programID, "imageID", imgID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", indexImagePath, "max_cache_days", images.MaxCacheAgeDays)
+				} else {
+					logger.Info("Proxy: cached image expired; refreshing",
+						"programID", programID, "imageID", imgID, "path", indexImagePath, "max_cache_days", images.MaxCacheAgeDays)
 				}
 			}
+		} else {
+			indexImageExpired = true
+			logger.Warn("Proxy: index stale, removing mapping", "programID", programID, "imageID", imgID)
+			_ = indexDelete(programID)
 		}
+	}
 
-		resultCh, isLeader := beginImageFetch(imageID)
-		if !isLeader {
-			outcome := <-resultCh
-			if outcome.err != nil {
-				if outcome.err.retryAfter > 0 {
-					w.Header().Set("Retry-After", fmt.Sprintf("%.0f", outcome.err.retryAfter.Seconds()))
-				}
-				http.Error(w, outcome.err.message, outcome.err.status)
-				return
+	// 2) Resolve via metadata (or fetch-on-miss)
+	chosen, ok := Cache.resolveSDImageForProgram(programID)
+	if !ok || chosen.URI == "" {
+		if !blockGlobal && ensureProgramMetadata(programID) {
+			if ch2, ok2 := Cache.resolveSDImageForProgram(programID); ok2 && ch2.URI != "" {
+				chosen = ch2
+				ok = true
 			}
+		}
+	}
+
+	useResolved := ok && chosen.URI != ""
+	if useResolved {
+		candidateID := sdImageIDFromURI(chosen.URI)
+		if !isSDImageID(candidateID) {
+			logger.Warn("Proxy: resolved non-SD image skipped", "programID", programID, "imageID", candidateID, "uri", chosen.URI)
+			useResolved = false
+			imageID = ""
 		} else {
-			// Leader performs the download, then notifies any waiters.
-			var fetchErr *imageFetchError
-			if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
-				fetchErr = nil
+			logger.Info("Proxy: resolved image candidate",
+				"programID", programID,
+				"imageID", candidateID,
+				"category", chosen.Category, "aspect", chosen.Aspect, "w", chosen.Width, "h", chosen.Height,
+				"uri", chosen.URI)
+			imageID = candidateID
+		}
+	}
+	if !useResolved {
+		imageID = indexImageID
+		if imageID == "" {
+			if blockGlobal {
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
+				http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
+				logger.Warn("Proxy: global pause in effect; no cached image available", "programID", programID, "remaining", blockRemain)
+			} else {
+				logger.Warn("Proxy: no suitable image in metadata", "programID", programID)
+				http.NotFound(w, r)
+			}
+			return
+		}
+		logger.Info("Proxy: using cached image id (no new metadata)", "programID", programID, "imageID", imageID)
+	}
+
+	if imageID != "" && !isSDImageID(imageID) {
+		logger.Warn("Proxy: non-SD image mapping ignored", "programID", programID, "imageID", imageID)
+		http.NotFound(w, r)
+		return
+	}
+
+	// During a global pause, still serve from cache if the resolved image is already on disk
+	// even when the programme→image index lacks an entry.
+	if blockGlobal && imageID != "" {
+		filePath := filepath.Join(folderImage, imageID+".jpg")
+		if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
+			lastTouch := indexLastRequestForImage(imageID)
+			if lastTouch.IsZero() {
+				lastTouch = fi.ModTime()
+			}
+			if maxAge > 0 && now.Sub(lastTouch) > maxAge {
+				logger.Info("Proxy: serve expired cache during global pause (resolved)",
+					"programID", programID, "imageID", imageID, "path", filePath,
+					"max_cache_days", images.MaxCacheAgeDays)
 			} else {
-				fetchErr = fetchAndCacheSDImage(programID, imageID, filePath)
+				logger.Info("Proxy: serve from cache during global pause (resolved)",
+					"programID", programID, "imageID", imageID, "path", filePath)
 			}
-			endImageFetch(imageID, imageFetchOutcome{err: fetchErr})
+			_ = indexSet(programID, imageID)
+			markProxyOutcome(w, "cache_hit")
+			serveFileCached(w, r, filePath)
+			return
+		}
+	}
 
-			if fetchErr != nil {
-				if fetchErr.retryAfter > 0 {
-					w.Header().Set("Retry-After", fmt.Sprintf("%.0f", fetchErr.retryAfter.Seconds()))
+	if blockGlobal {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockRemain.Seconds()))
+		http.Error(w, "image downloads paused due to upstream limits", http.StatusTooManyRequests)
+		logger.Warn("Proxy: global pause in effect; denying image download", "programID", programID, "remaining", blockRemain)
+		return
+	}
+
+	// 3) Serve from disk if present (and update index) provided it hasn't expired
+	filePath := filepath.Join(folderImage, imageID+".jpg")
+	if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
+		lastTouch := indexLastRequestForImage(imageID)
+		if lastTouch.IsZero() {
+			lastTouch = fi.ModTime()
+		}
+		purged := false
+		if purgeEnabled && now.Sub(lastTouch) > purgeThreshold {
+			logger.Info("Proxy: purging stale cached image",
+				"programID", programID, "imageID", imageID, "path", filePath,
+				"last_request_utc", lastTouch.UTC(), "purge_after_days", purgeAfterDays)
+			if err := os.Remove(filePath); err != nil {
+				logger.Warn("Proxy: failed to remove stale cached image",
+					"programID", programID, "imageID", imageID, "path", filePath, "error", err)
+			} else {
+				if err := indexDeleteImageIDs([]string{imageID}); err != nil {
+					logger.Warn("Proxy: failed to prune index for stale cached image", "imageID", imageID, "error", err)
 				}
-				http.Error(w, fetchErr.message, fetchErr.status)
+			}
+			purged = true
+		}
+		if !purged {
+			expired := false
+			if maxAge > 0 && now.Sub(lastTouch) > maxAge {
+				expired = true
+			}
+			if !expired {
+				if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID); ok {
+					logger.Info("Proxy: serve from cache (by imageID)",
+						"programID", programID, "imageID", imageID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
+				} else {
+					logger.Info("Proxy: serve from cache (by imageID, no meta)",
+						"programID", programID, "imageID", imageID, "path", filePath)
+				}
+				_ = indexSet(programID, imageID)
+				markProxyOutcome(w, "cache_hit")
+				serveFileCached(w, r, filePath)
 				return
 			}
+			if !(indexImageExpired && indexImageID == imageID) {
+				logger.Info("Proxy: cached candidate expired; refreshing", "programID", programID, "imageID", imageID, "path", filePath)
+			}
 		}
+	}
 
-		// Update index and serve (log with category if possible)
-		_ = indexSet(programID, imageID)
-		if _, _, _, _, ok := lookupImageMeta(programID, imageID); !ok {
-			_ = ensureProgramMetadata(programID)
+	resultCh, isLeader := beginImageFetch(imageID)
+	if !isLeader {
+		outcome := <-resultCh
+		if outcome.err != nil {
+			if outcome.err.retryAfter > 0 {
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", outcome.err.retryAfter.Seconds()))
+			}
+			http.Error(w, outcome.err.message, outcome.err.status)
+			return
 		}
-		if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID); ok {
-			logger.Info("Proxy: serve freshly cached",
-				"programID", programID, "imageID", imageID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
+	} else {
+		// Leader performs the download, then notifies any waiters.
+		var fetchErr *imageFetchError
+		if fi, err := os.Stat(filePath); err == nil && !fi.IsDir() {
+			fetchErr = nil
 		} else {
-			logger.Info("Proxy: serve freshly cached (no meta)",
-				"programID", programID, "imageID", imageID, "path", filePath)
+			fetchErr = fetchAndCacheSDImage(programID, imageID, filePath)
 		}
-		serveFileCached(w, r, filePath)
-	})
+		endImageFetch(imageID, imageFetchOutcome{err: fetchErr})
 
-	// Static server
-	fs := http.FileServer(http.Dir(dir))
-	mux.Handle("/", fs)
+		if fetchErr != nil {
+			if fetchErr.retryAfter > 0 {
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", fetchErr.retryAfter.Seconds()))
+			}
+			http.Error(w, fetchErr.message, fetchErr.status)
+			return
+		}
+	}
 
-	logger.Info("Starting server", "address", "http://"+Config.Server.Address+":"+port, "serving", filepath.Clean(dir))
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
-		logger.Error("Server failed to start", "error", err)
+	// Update index and serve (log with category if possible)
+	_ = indexSet(programID, imageID)
+	if _, _, _, _, ok := lookupImageMeta(programID, imageID); !ok {
+		_ = ensureProgramMetadata(programID)
+	}
+	if cat, asp, wpx, hpx, ok := lookupImageMeta(programID, imageID); ok {
+		logger.Info("Proxy: serve freshly cached",
+			"programID", programID, "imageID", imageID, "category", cat, "aspect", asp, "w", wpx, "h", hpx, "path", filePath)
+	} else {
+		logger.Info("Proxy: serve freshly cached (no meta)",
+			"programID", programID, "imageID", imageID, "path", filePath)
 	}
+	markProxyOutcome(w, "download")
+	serveFileCached(w, r, filePath)
+}
+
+// isChosenSDImage reports whether imageID is the image /proxy/sd/{programID}
+// chooses with the Poster Aspect from Options. Only that image may go into the
+// index, or the unpinned URL would serve the aspect of another output.
+func isChosenSDImage(programID, imageID string) bool {
+	chosenID, _, ok := Cache.GetChosenSDImage(programID)
+	return ok && chosenID == imageID
 }
 
 func purgeStalePosterFiles(dir string, cacheDays int) (int, error) {
@@ -907,6 +1017,107 @@ func serveFileCached(w http.ResponseWriter, r *http.Request, path string) {
 	http.ServeContent(w, r, fi.Name(), modTime, f)
 }
 
+// staticHandler serves dir like http.FileServer, but hands the configured XMLTV
+// files and their .gz/.xz copies to serveXMLTVFile for the proper headers. The
+// config is read per request, so outputs added by a reload are served too.
+func staticHandler(dir string) http.Handler {
+	fs := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".xml", ".gz", ".xz":
+			if filePath, ok := configuredXMLTVFile(dir, name); ok {
+				serveXMLTVFile(w, r, filePath)
+				return
+			}
+		}
+		fs.ServeHTTP(w, r)
+	})
+}
+
+// configuredXMLTVFile returns the XMLTV file (or compressed copy) of an output
+// that the request path names, either by its route "/<file name>" or by its
+// path inside the static dir.
+func configuredXMLTVFile(dir, name string) (string, bool) {
+	cfg := currentConfig()
+	inDir := filepath.Join(dir, filepath.FromSlash(name))
+
+	for _, profile := range cfg.xmltvProfiles() {
+		xmltv := strings.TrimSpace(profile.XMLTV)
+		if xmltv == "" {
+			continue
+		}
+		for _, suffix := range []string{"", ".gz", ".xz"} {
+			filePath := xmltv + suffix
+			if name == "/"+filepath.Base(filePath) || inDir == filepath.Clean(filePath) {
+				return filePath, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// serveXMLTVFile serves an XMLTV file or one of its compressed copies.
+//   - *.gz and *.xz are served as archives (application/gzip, application/x-xz).
+//   - *.xml is served from the sibling *.xml.gz with Content-Encoding: gzip when
+//     the client accepts gzip and the copy is not older than the plain file.
+func serveXMLTVFile(w http.ResponseWriter, r *http.Request, filePath string) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+
+	case ".gz":
+		w.Header().Set("Content-Type", "application/gzip")
+
+	case ".xz":
+		w.Header().Set("Content-Type", "application/x-xz")
+
+	default:
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if acceptsGzip(r) {
+			plain, perr := os.Stat(filePath)
+			gz, gerr := os.Stat(filePath + ".gz")
+			if perr == nil && gerr == nil && !gz.ModTime().Before(plain.ModTime()) {
+				w.Header().Set("Content-Encoding", "gzip")
+				filePath += ".gz"
+			}
+		}
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+// acceptsGzip reports whether the client lists gzip in Accept-Encoding (and does not refuse it with q=0).
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), "gzip") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if q := strings.TrimSpace(f); q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 // looksLikeImage does a minimal magic check so we don't save JSON/HTML as .jpg
 func looksLikeImage(b []byte) bool {
 	if len(b) < 12 {
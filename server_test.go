@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestServeXMLTVFileGzipVariant(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "config.xml")
+	plain := []byte("<tv></tv>\n")
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(plain)
+	zw.Close()
+
+	if err := os.WriteFile(plainPath, plain, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(plainPath+".gz", gz.Bytes(), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		file         string
+		accept       string
+		wantType     string
+		wantEncoding string
+		wantBody     []byte
+	}{
+		{
+			name:     "plain without accept-encoding",
+			file:     plainPath,
+			wantType: "application/xml; charset=utf-8",
+			wantBody: plain,
+		},
+		{
+			name:         "plain served from gzip copy",
+			file:         plainPath,
+			accept:       "br, gzip",
+			wantType:     "application/xml; charset=utf-8",
+			wantEncoding: "gzip",
+			wantBody:     gz.Bytes(),
+		},
+		{
+			name:     "gzip refused with q=0",
+			file:     plainPath,
+			accept:   "gzip;q=0",
+			wantType: "application/xml; charset=utf-8",
+			wantBody: plain,
+		},
+		{
+			name:     "gzip copy requested directly",
+			file:     plainPath + ".gz",
+			accept:   "gzip",
+			wantType: "application/gzip",
+			wantBody: gz.Bytes(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+filepath.Base(tt.file), nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			serveXMLTVFile(rec, req, tt.file)
+
+			res := rec.Result()
+			body, _ := io.ReadAll(res.Body)
+			if got := res.Header.Get("Content-Type"); got != tt.wantType {
+				t.Fatalf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := res.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if !bytes.Equal(body, tt.wantBody) {
+				t.Fatalf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStaticHandlerXMLTVFiles(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir, outside := t.TempDir(), t.TempDir()
+	for _, file := range []string{
+		filepath.Join(dir, "config.xml"),
+		filepath.Join(dir, "other.xml"),
+		filepath.Join(outside, "plex.xml"),
+	} {
+		if err := os.WriteFile(file, []byte("<tv></tv>\n"), 0644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	Config = config{File: filepath.Join(dir, "config")}
+	Config.Files.XMLTV = filepath.Join(dir, "config.xml")
+	handler := staticHandler(dir)
+
+	get := func(target string) *http.Response {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Result()
+	}
+
+	// serveXMLTVFile adds Vary: Accept-Encoding, the file server does not
+	if res := get("/config.xml"); res.StatusCode != http.StatusOK || res.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("/config.xml: status %d, Vary %q; want the XMLTV file", res.StatusCode, res.Header.Get("Vary"))
+	}
+	if res := get("/other.xml"); res.StatusCode != http.StatusOK || res.Header.Get("Vary") != "" {
+		t.Errorf("/other.xml: status %d, Vary %q; want a plain static file", res.StatusCode, res.Header.Get("Vary"))
+	}
+	if res := get("/plex.xml"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("/plex.xml before it is configured: status %d, want 404", res.StatusCode)
+	}
+
+	// An output added by a reload is served by its route
+	Config.Outputs = []outputProfile{{Name: "plex", XMLTV: filepath.Join(outside, "plex.xml")}}
+	if res := get("/plex.xml"); res.StatusCode != http.StatusOK || res.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("/plex.xml: status %d, Vary %q; want the output file", res.StatusCode, res.Header.Get("Vary"))
+	}
+}
+
+func TestProxyPinnedImageIndex(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	Cache.Lock()
+	originalMetadata := Cache.Metadata
+	Cache.Unlock()
+	resetIndex := func() {
+		indexMu.Lock()
+		indexOnce, indexLoaded, indexMap = sync.Once{}, false, nil
+		indexMu.Unlock()
+	}
+	defer func() {
+		Cache.Lock()
+		Cache.Metadata = originalMetadata
+		Cache.Unlock()
+		Config = original
+		overridesReload()
+		resetIndex()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	aspect := "16x9"
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+	Config.Options.Images.Path = filepath.Join(dir, "images")
+	Config.Options.Images.PosterAspect = "2x3"
+	Config.Outputs = []outputProfile{{Name: "jellyfin", PosterAspect: &aspect}}
+	resetIndex()
+
+	const programID = "EP012345670001"
+	var m EPGoCache
+	if err := json.Unmarshal([]byte(`{"data": [
+		{"uri": "assets/p301122_b_v8_aa.jpg", "category": "Poster Art", "aspect": "2x3", "width": 240, "height": 360},
+		{"uri": "assets/p301122_b_h6_ab.jpg", "category": "Poster Art", "aspect": "16x9", "width": 1280, "height": 720}]}`), &m); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	Cache.Lock()
+	Cache.Metadata = map[string]EPGoCache{programID: m}
+	Cache.Unlock()
+
+	if err := os.MkdirAll(Config.Options.Images.Path, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() error = %v", err)
+	}
+
+	// The pinned URL of each output, as the XMLTV files carry them
+	profiles := Config.xmltvProfiles()
+	pinned := make([]string, len(profiles))
+	for i, profile := range profiles {
+		imageID, _, ok := Cache.GetChosenSDImageForAspect(programID, profile.PosterAspect)
+		if !ok {
+			t.Fatalf("no image chosen for %s (%s)", profile.Name, profile.PosterAspect)
+		}
+		if err := os.WriteFile(filepath.Join(Config.Options.Images.Path, imageID+".jpg"), []byte(profile.PosterAspect), 0644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		pinned[i] = "/proxy/sd/" + programID + "/" + imageID
+	}
+	mainURL, jellyfinURL := pinned[0], pinned[1]
+
+	serve := func(path string) string {
+		rec := httptest.NewRecorder()
+		proxySDHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d", path, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if got := serve(jellyfinURL); got != "16x9" {
+		t.Errorf("GET %s = %q, want the 16x9 image", jellyfinURL, got)
+	}
+	if imageID, ok := indexGet(programID); ok {
+		t.Errorf("16x9 output indexed %s", imageID)
+	}
+
+	serve(mainURL)
+	serve(jellyfinURL)
+	if imageID, _ := indexGet(programID); imageID != "p301122_b_v8_aa" {
+		t.Errorf("index = %q, want the 2x3 image p301122_b_v8_aa", imageID)
+	}
+
+	if got := serve("/proxy/sd/" + programID); got != "2x3" {
+		t.Errorf("unpinned URL = %q, want the 2x3 image", got)
+	}
+}
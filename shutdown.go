@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"epgo/tmdb"
+)
+
+// shutdownTimeout stays below the 30 seconds the cron wrapper waits before SIGKILL.
+const shutdownTimeout = 25 * time.Second
+
+var (
+	httpServerMu sync.Mutex
+	httpServer   *http.Server
+)
+
+func setHTTPServer(srv *http.Server) {
+	httpServerMu.Lock()
+	httpServer = srv
+	httpServerMu.Unlock()
+}
+
+// handleSignals installs the signal handler: SIGTERM and SIGINT shut down
+// gracefully and exit 0, SIGHUP reloads the config and the overrides file.
+func handleSignals() {
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	go func() {
+		for sig := range ch {
+			if sig == syscall.SIGHUP {
+				reloadOnSignal()
+				continue
+			}
+
+			logger.Info("Shutting down", "signal", sig.String())
+			shutdown(time.Now().Add(shutdownTimeout))
+			logger.Info("Shutdown complete")
+			os.Exit(0)
+		}
+	}()
+}
+
+// shutdown stops accepting requests, lets in-flight requests (and their image
+// downloads) and a running refresh finish until the deadline, then flushes the
+// image index and the TMDb cache.
+func shutdown(deadline time.Time) {
+
+	httpServerMu.Lock()
+	srv := httpServer
+	httpServerMu.Unlock()
+
+	if srv != nil {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warn("HTTP server did not drain in time", "error", err)
+		}
+		cancel()
+	}
+
+	// Holding refreshMu also keeps the daemon from starting another refresh
+	if waitForRefresh(deadline) {
+		logger.Info("No refresh running")
+	} else {
+		logger.Warn("Refresh still running at shutdown; the cache keeps its last saved state")
+	}
+
+	if indexLoaded {
+		if err := indexSave(); err != nil {
+			logger.Error("unable to save the image index", "error", err)
+		}
+	}
+
+	if err := tmdb.Flush(); err != nil {
+		logger.Error("unable to save the TMDb cache", "error", err)
+	}
+}
+
+// waitForRefresh acquires refreshMu before the deadline and keeps it.
+func waitForRefresh(deadline time.Time) bool {
+	for {
+		if refreshMu.TryLock() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// reloadOnSignal reloads the overrides file and, unless a refresh is running
+// (which re-reads the config itself), the config file.
+func reloadOnSignal() {
+
+	overridesReload()
+
+	// -serve runs without a config file
+	if len(currentConfig().File) == 0 {
+		logger.Info("SIGHUP: overrides reloaded")
+		return
+	}
+
+	if !refreshMu.TryLock() {
+		logger.Info("SIGHUP: overrides reloaded; refresh running, it reads the config file itself")
+		return
+	}
+	defer refreshMu.Unlock()
+
+	if err := reloadConfig(); err != nil {
+		logger.Error("SIGHUP: unable to reload the config", "filename", configFileName(), "error", err)
+		return
+	}
+
+	logger.Info("SIGHUP: config and overrides reloaded", "filename", configFileName())
+}
+
+// serveUntilShutdown runs srv; after a graceful shutdown it blocks, because
+// the signal handler flushes the caches and exits the process.
+func serveUntilShutdown(srv *http.Server) error {
+
+	setHTTPServer(srv)
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		select {}
+	}
+
+	return err
+}
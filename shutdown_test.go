@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Drop a stale wake-up from other tests
+	select {
+	case <-configReloaded:
+	default:
+	}
+
+	dir := t.TempDir()
+	Config.File = filepath.Join(dir, "config")
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+	if err := os.WriteFile(Config.File+".yaml", []byte("Options:\n  Schedule Days: 3\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// A running refresh reads the config file itself
+	refreshMu.Lock()
+	reloadOnSignal()
+	refreshMu.Unlock()
+
+	if Config.Options.Schedule == 3 {
+		t.Errorf("config reloaded while a refresh was running")
+	}
+
+	reloadOnSignal()
+
+	if Config.Options.Schedule != 3 {
+		t.Errorf("Schedule Days = %d after SIGHUP, want 3", Config.Options.Schedule)
+	}
+
+	select {
+	case <-configReloaded:
+	default:
+		t.Errorf("daemon loop was not woken after the reload")
+	}
+}
@@ -11,6 +11,9 @@ type cache struct {
 	Metadata map[string]EPGoCache   `json:"Metadata"`
 	Schedule map[string][]EPGoCache `json:"Schedule"`
 
+	// ScheduleMD5 : stationID -> date (YYYY-MM-DD) -> md5 of the cached schedule day
+	ScheduleMD5 map[string]map[string]string `json:"ScheduleMD5"`
+
 	sync.RWMutex `json:"-"`
 }
 
@@ -26,6 +29,7 @@ type EPGoCache struct {
 	Name              string   `json:"name,omitempty"`
 	Callsign          string   `json:"callsign,omitempty"`
 	Affiliate         string   `json:"affiliate,omitempty"`
+	ChannelNumber     string   `json:"channelNumber,omitempty"`
 	BroadcastLanguage []string `json:"broadcastLanguage"`
 	StationLogo       []struct {
 		URL    string `json:"URL"`
@@ -96,7 +100,7 @@ type EPGoCache struct {
 			Episode int `json:"episode"`
 			Season  int `json:"season"`
 		} `json:"Gracenote"`
-	} `json:"metadata",omitempty`
+	} `json:"metadata,omitempty"`
 
 	OriginalAirDate string `json:"originalAirDate,omitempty"`
 	ResourceID      string `json:"resourceID,omitempty"`
@@ -128,4 +132,9 @@ type SDSchedule struct {
 		VideoProperties []string `json:"videoProperties"`
 	} `json:"programs"`
 	StationID string `json:"stationID"`
+	Metadata  struct {
+		Modified  string `json:"modified"`
+		Md5       string `json:"md5"`
+		StartDate string `json:"startDate"`
+	} `json:"metadata"`
 }
@@ -1,8 +1,14 @@
 package main
 
+import "time"
+
 type config struct {
-	File       string   `yaml:"-"`
-	ChannelIDs []string `yaml:"-"`
+	File           string    `yaml:"-"`
+	ChannelIDs     []string  `yaml:"-"`
+	FilterStations []channel `yaml:"-"` // stations chosen by the Channel Filter on the last refresh
+
+	// Output Timezone (see loadOutputTimezone)
+	outputLocation *time.Location
 
 	Account struct {
 		Username string `yaml:"Username" json:"username"`
@@ -12,6 +18,8 @@ type config struct {
 	Files struct {
 		Cache         string `yaml:"Cache"`
 		XMLTV         string `yaml:"XMLTV"`
+		XMLTVGzip     bool   `yaml:"XMLTV gzip"` // also write <XMLTV>.gz
+		XMLTVXz       bool   `yaml:"XMLTV xz"`   // also write <XMLTV>.xz
 		TmdbCacheFile string `yaml:"The MovieDB cache file"`
 	} `yaml:"Files"`
 
@@ -19,14 +27,34 @@ type config struct {
 		Enable  bool   `yaml:"Enable"`
 		Address string `yaml:"Address"`
 		Port    string `yaml:"Port"`
+
+		// Web UI on /admin/ for the config, lineups, channels and poster overrides
+		Admin struct {
+			Enable   bool   `yaml:"Enable"`
+			Username string `yaml:"Username"`
+			Password string `yaml:"Password"` // HTTP basic auth; the UI stays off while empty
+		} `yaml:"Admin UI"`
+
+		APIToken string `yaml:"API Token"` // bearer token for /api/; the API stays off while empty
+
+		// Thresholds of /readyz; 0 disables a check
+		Readiness struct {
+			MaxXMLTVAgeHours  int `yaml:"Max XMLTV age hours"`
+			AccountExpiryDays int `yaml:"Account expiry warning days"`
+		} `yaml:"Readiness"`
 	} `yaml:"Server"`
 
 	Options struct {
-		LiveIcons               bool `yaml:"Live and New icons"`
-		Schedule                int  `yaml:"Schedule Days"`
-		SkipRefreshHours        int  `yaml:"Skip EPG refresh if XMLTV younger than hours"`
-		SubtitleIntoDescription bool `yaml:"Subtitle into Description"`
-		Credits                 bool `yaml:"Insert credits tag into XML file"`
+		LiveIcons               bool   `yaml:"Live and New icons"`
+		Schedule                int    `yaml:"Schedule Days"`
+		ScheduleDayOffset       int    `yaml:"Schedule Day Offset"`            // days from today the schedule starts (0 = today)
+		PastHours               int    `yaml:"Keep past programmes for hours"` // hours of aired programmes kept in the XMLTV file
+		SkipRefreshHours        int    `yaml:"Skip EPG refresh if XMLTV younger than hours"`
+		RefreshSchedule         string `yaml:"Refresh Schedule"` // cron expression used by -daemon
+		SortChannelsBy          string `yaml:"Sort Channels By"` // config | number
+		OutputTimezone          string `yaml:"Output Timezone"`  // IANA name for programme start/stop, e.g. Europe/Berlin
+		SubtitleIntoDescription bool   `yaml:"Subtitle into Description"`
+		Credits                 bool   `yaml:"Insert credits tag into XML file"`
 		Images                  struct {
 			Download     bool   `yaml:"Download Images from Schedules Direct"`
 			Path         string `yaml:"Image Path"`
@@ -59,6 +87,45 @@ type config struct {
 	} `yaml:"Options"`
 
 	Station []channel `yaml:"Station"`
+
+	// Rules that add lineup stations automatically on every refresh
+	ChannelFilter struct {
+		Include []channelRule `yaml:"Include,omitempty"`
+		Exclude []channelRule `yaml:"Exclude,omitempty"`
+	} `yaml:"Channel Filter,omitempty"`
+
+	VirtualStation []virtualStation `yaml:"Virtual Station,omitempty"`
+
+	// Additional XMLTV files written from the same cache (e.g. one per client)
+	Outputs []outputProfile `yaml:"Outputs,omitempty"`
+}
+
+// outputProfile is an additional XMLTV file written after the main one. Options
+// that are left out inherit the values from Files and Options.
+type outputProfile struct {
+	Name         string   `yaml:"Name"`
+	XMLTV        string   `yaml:"XMLTV"`
+	XMLTVGzip    bool     `yaml:"XMLTV gzip,omitempty"`
+	XMLTVXz      bool     `yaml:"XMLTV xz,omitempty"`
+	Stations     []string `yaml:"Stations,omitempty"` // station and virtual station IDs; empty = all
+	PosterAspect *string  `yaml:"Poster Aspect,omitempty"`
+	Credits      *bool    `yaml:"Insert credits tag into XML file,omitempty"`
+	Rating       *bool    `yaml:"Insert rating tag into XML file,omitempty"`
+	LiveIcons    *bool    `yaml:"Live and New icons,omitempty"`
+	Icons        *bool    `yaml:"Programme icons,omitempty"`
+}
+
+// virtualStation is an XMLTV channel that clones the schedule of a configured
+// station (Source) with a fixed time offset, e.g. a "+1" feed that Schedules
+// Direct does not list separately. No extra SD requests are made for it.
+type virtualStation struct {
+	Name         string   `yaml:"Name"`
+	ID           string   `yaml:"ID"`          // XMLTV channel ID
+	Source       string   `yaml:"Source"`      // station ID of the original station
+	TimeOffset   float64  `yaml:"Time Offset"` // hours, e.g. 1 for "+1"
+	Number       string   `yaml:"Number,omitempty"`
+	Logo         string   `yaml:"Logo,omitempty"`
+	DisplayNames []string `yaml:"Display Names,omitempty"`
 }
 
 type channel struct {
@@ -66,6 +133,14 @@ type channel struct {
 	DisplayName []DisplayName `yaml:"-" json:"-" xml:"display-name"`
 	ID          string        `yaml:"ID" json:"stationID" xml:"id,attr"`
 	Lineup      string        `yaml:"Lineup" json:"-" xml:"-"`
+	Number      string        `yaml:"Number,omitempty" json:"-" xml:"-"` // optional channel number used by "Sort Channels By: number"
 	Date        []string      `yaml:"-" json:"date"`
 	Icon        Icon          `yaml:"-" json:"-" xml:"icon"`
+	LCN         string        `yaml:"-" json:"-" xml:"lcn,omitempty"`
+
+	// Optional per-station XMLTV customization
+	XMLTVID      string   `yaml:"XMLTV ID,omitempty" json:"-" xml:"-"`      // replaces <ID>.schedulesdirect.org
+	Logo         string   `yaml:"Logo,omitempty" json:"-" xml:"-"`          // logo URL instead of the SD logo
+	DisplayNames []string `yaml:"Display Names,omitempty" json:"-" xml:"-"` // extra <display-name> entries
+	TimeOffset   float64  `yaml:"Time Offset,omitempty" json:"-" xml:"-"`   // hours added to every programme, e.g. 1 for a "+1" feed
 }
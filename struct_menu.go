@@ -17,4 +17,5 @@ type Entry struct {
 	Postalcode string
 	ShortName  string
 	Lineup     string
+	Transport  string
 }
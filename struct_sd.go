@@ -65,44 +65,17 @@ type SD struct {
 			} `json:"systemStatus"`
 		}
 
-		// Countries
-		Countries struct {
-			Caribbean []struct {
-				FullName          string `json:"fullName"`
-				OnePostalCode     bool   `json:"onePostalCode"`
-				PostalCode        string `json:"postalCode"`
-				PostalCodeExample string `json:"postalCodeExample"`
-				ShortName         string `json:"shortName"`
-			} `json:"Caribbean"`
-			Europe []struct {
-				FullName          string `json:"fullName"`
-				OnePostalCode     bool   `json:"onePostalCode"`
-				PostalCode        string `json:"postalCode"`
-				PostalCodeExample string `json:"postalCodeExample"`
-				ShortName         string `json:"shortName"`
-			} `json:"Europe"`
-			LatinAmerica []struct {
-				FullName          string `json:"fullName"`
-				OnePostalCode     bool   `json:"onePostalCode"`
-				PostalCode        string `json:"postalCode"`
-				PostalCodeExample string `json:"postalCodeExample"`
-				ShortName         string `json:"shortName"`
-			} `json:"Latin America"`
-			NorthAmerica []struct {
-				FullName          string `json:"fullName"`
-				PostalCode        string `json:"postalCode"`
-				PostalCodeExample string `json:"postalCodeExample"`
-				ShortName         string `json:"shortName"`
-			} `json:"North America"`
-			Zzz []struct {
-				FullName          string `json:"fullName"`
-				OnePostalCode     bool   `json:"onePostalCode"`
-				PostalCode        string `json:"postalCode"`
-				PostalCodeExample string `json:"postalCodeExample"`
-				ShortName         string `json:"shortName"`
-			} `json:"ZZZ"`
+		// Countries (region -> countries, e.g. "North America", "Europe", "Oceania")
+		Countries map[string][]SDCountry
+
+		// Satellites (DVB-S)
+		Satellites []struct {
+			Lineup string `json:"lineup"`
 		}
 
+		// Transmitters (antenna, name -> lineup)
+		Transmitters map[string]string
+
 		// Headend
 		Headend []struct {
 			Headend string `json:"headend"`
@@ -126,24 +99,24 @@ type SD struct {
 			ServerID         string    `json:"serverID"`
 
 			// GET
-			Map []struct {
-				StationID string `json:"stationID"`
-				Channel   string `json:"channel"`
-			} `json:"map"`
-			Stations []Station `json:"stations"`
+			Map      []SDChannelMap `json:"map"`
+			Stations []Station      `json:"stations"`
 		}
 	}
 
 	// SD API Calls
-	Login     func() (err error)
-	Status    func() (err error)
-	Countries func() (err error)
-	Headends  func() (err error)
-	Lineups   func() (err error)
-	Delete    func() (err error)
-	Channels  func() (err error)
-	Schedule  func() (err error)
-	Program   func() (err error)
+	Login        func() (err error)
+	Status       func() (err error)
+	Countries    func() (err error)
+	Satellites   func() (err error)
+	Transmitters func() (err error)
+	Headends     func() (err error)
+	Lineups      func() (err error)
+	Delete       func() (err error)
+	Channels     func() (err error)
+	Schedule     func() (err error)
+	ScheduleMD5  func() (err error)
+	Program      func() (err error)
 }
 
 // Station : Station SD API
@@ -236,7 +209,7 @@ type SDProgram struct {
 
 // SDMetadata : Schedules Direct meta data
 type SDMetadata struct {
-	Data      []Data `json:"data",required`
+	Data      []Data `json:"data"`
 	ProgramID string `json:"programID"`
 }
 
@@ -252,10 +225,7 @@ type Data struct {
 
 // SDStation : Schedules Direct stations
 type SDStation struct {
-	Map []struct {
-		Channel   string `json:"channel"`
-		StationID string `json:"stationID"`
-	} `json:"map"`
+	Map      []SDChannelMap `json:"map"`
 	Metadata struct {
 		Lineup    string `json:"lineup"`
 		Modified  string `json:"modified"`
@@ -290,6 +260,32 @@ type SDStation struct {
 	} `json:"stations"`
 }
 
+// SDScheduleMD5 : Schedules Direct schedule MD5s (stationID -> date -> md5)
+type SDScheduleMD5 map[string]map[string]struct {
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	LastModified string `json:"lastModified"`
+	Md5          string `json:"md5"`
+}
+
+// SDCountry : Schedules Direct country (available/countries)
+type SDCountry struct {
+	FullName          string `json:"fullName"`
+	ShortName         string `json:"shortName"`
+	PostalCode        string `json:"postalCode"`
+	PostalCodeExample string `json:"postalCodeExample"`
+	OnePostalCode     bool   `json:"onePostalCode"`
+}
+
+// SDChannelMap : Lineup map entry (station -> channel number)
+type SDChannelMap struct {
+	StationID            string `json:"stationID"`
+	Channel              string `json:"channel"`
+	LogicalChannelNumber string `json:"logicalChannelNumber,omitempty"`
+	AtscMajor            int    `json:"atscMajor,omitempty"`
+	AtscMinor            int    `json:"atscMinor,omitempty"`
+}
+
 // SDError : Errors from SD
 type SDError struct {
 	Data struct {
@@ -119,7 +119,7 @@ type ProgramIcon struct {
 type Rating struct {
 	System string `xml:"system,attr"`
 	Value  string `xml:"value"`
-	Icon   []Icon `xml:"icon",omitempty`
+	Icon   []Icon `xml:"icon,omitempty"`
 }
 
 type Video struct {
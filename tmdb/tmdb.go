@@ -10,7 +10,10 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"epgo/internal/atomicfile"
 )
 
 const (
@@ -32,6 +35,28 @@ var (
 	caches sync.Map
 )
 
+// Stats counts SearchItem lookups by result since the process started.
+type Stats struct {
+	CacheHits uint64 // answered from the cache file
+	Hits      uint64 // poster found via the TMDb API
+	Misses    uint64 // TMDb has no poster
+	Errors    uint64 // lookup failed
+}
+
+var stats struct {
+	cacheHits, hits, misses, errors atomic.Uint64
+}
+
+// GetStats returns the lookup counters.
+func GetStats() Stats {
+	return Stats{
+		CacheHits: stats.cacheHits.Load(),
+		Hits:      stats.hits.Load(),
+		Misses:    stats.misses.Load(),
+		Errors:    stats.errors.Load(),
+	}
+}
+
 // isV4Token detects whether the provided TMDb credential looks like a v4 read
 // access token (JWT). v3 keys are short (32 chars) and should be sent as a
 // query parameter, while v4 tokens are long JWT strings that belong in the
@@ -113,13 +138,16 @@ func SearchItem(logger *slog.Logger, searchTerm, mediaType, tmdbApiKey, imageCac
 	// 3) Cache hit?
 	cache, err := getCache(imageCacheFile)
 	if err != nil {
+		stats.errors.Add(1)
 		return "", fmt.Errorf("tmdb: error preparing cache: %w", err)
 	}
 
 	cachedKey := origTerm + "-" + mediaType
 	if cachedPath, err := cache.getImageURL(cachedKey); err != nil {
+		stats.errors.Add(1)
 		return "", fmt.Errorf("tmdb: error checking cache: %w", err)
 	} else if cachedPath != "" {
+		stats.cacheHits.Add(1)
 		return posterURL(cachedPath, ""), nil // default w500
 	}
 	fetchLogOnce.Do(func() {
@@ -216,12 +244,15 @@ func SearchItem(logger *slog.Logger, searchTerm, mediaType, tmdbApiKey, imageCac
 	if posterPath == "" {
 		// if there was a hard error and *also* no result, surface the error to help debugging
 		if lastErr != nil {
+			stats.errors.Add(1)
 			return "", fmt.Errorf("tmdb lookup failed: %w", lastErr)
 		}
 		// non-200 without a concrete error: keep quiet; upstream can decide how to log
 		if lastHTTPStatus != 0 && lastHTTPStatus != http.StatusOK {
+			stats.errors.Add(1)
 			return "", fmt.Errorf("tmdb returned HTTP %d with no usable results", lastHTTPStatus)
 		}
+		stats.misses.Add(1)
 		// Cache negative result to avoid hammering TMDb for items that have no poster
 		if err := cache.cacheNoPoster(cachedKey); err != nil {
 			logger.Warn("tmdb: unable to cache negative result", "error", err)
@@ -229,6 +260,8 @@ func SearchItem(logger *slog.Logger, searchTerm, mediaType, tmdbApiKey, imageCac
 		return "", nil
 	}
 
+	stats.hits.Add(1)
+
 	// 7) Cache the *path* (not full URL)
 	if err := cache.addImageToCache(origTerm+"-"+mediaType, posterPath); err != nil {
 		logger.Error("tmdb: error adding to cache", "error", err)
@@ -305,22 +338,27 @@ func (c *imageCache) addImageToCache(name, url string) error {
 	}
 
 	c.entries[name] = url
-
-	// Take a snapshot so we can write to disk without holding the primary lock
-	snapshot := make(map[string]string, len(c.entries))
-	for n, u := range c.entries {
-		snapshot[n] = u
-	}
 	c.mu.Unlock()
 
+	return c.save()
+}
+
+// save writes the cache file. The snapshot is taken under saveMu, so the last
+// writer always stores the newest entries.
+func (c *imageCache) save() error {
 	c.saveMu.Lock()
 	defer c.saveMu.Unlock()
 
-	f, err := os.OpenFile(c.filePath, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return fmt.Errorf("error opening image cache file: %w", err)
+	c.mu.RLock()
+	if !c.loaded {
+		c.mu.RUnlock()
+		return nil
 	}
-	defer f.Close()
+	snapshot := make(map[string]string, len(c.entries))
+	for n, u := range c.entries {
+		snapshot[n] = u
+	}
+	c.mu.RUnlock()
 
 	// Re-encode entire map to keep file consistent with in-memory state
 	cacheSlice := make([]map[string]string, 0, len(snapshot))
@@ -328,20 +366,29 @@ func (c *imageCache) addImageToCache(name, url string) error {
 		cacheSlice = append(cacheSlice, map[string]string{"name": n, "url": u})
 	}
 
-	if _, err := f.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("error seeking to beginning of file: %w", err)
-	}
-	if err := f.Truncate(0); err != nil {
-		return fmt.Errorf("error truncating file: %w", err)
-	}
-
-	enc := json.NewEncoder(f)
-	if err := enc.Encode(cacheSlice); err != nil {
+	blob, err := json.Marshal(cacheSlice)
+	if err != nil {
 		return fmt.Errorf("error encoding JSON: %w", err)
 	}
+	if err := atomicfile.WriteFile(c.filePath, append(blob, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing image cache file: %w", err)
+	}
 	return nil
 }
 
+// Flush writes every loaded cache file, waiting for writes in progress. It is
+// called before the process exits.
+func Flush() error {
+	var firstErr error
+	caches.Range(func(_, v any) bool {
+		if err := v.(*imageCache).save(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return true
+	})
+	return firstErr
+}
+
 func (c *imageCache) cacheNoPoster(name string) error {
 	return c.addImageToCache(name, noPosterSentinel)
 }
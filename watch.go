@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// The overrides file and the config file are polled for changes, so edits on
+// disk apply without a restart or SIGHUP. Polling also works on bind mounts
+// and network shares, where inotify events often never arrive.
+
+const fileWatchInterval = 10 * time.Second
+
+var fileWatchOnce sync.Once
+
+// fileStamp is what the watcher compares to notice a changed file.
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+}
+
+type fileWatcher struct {
+	overrides fileStamp
+	config    fileStamp
+}
+
+func newFileWatcher() *fileWatcher {
+	fw := &fileWatcher{overrides: statFile(overridesFilePath())}
+	if len(currentConfig().File) != 0 {
+		fw.config = statFile(configFileName())
+	}
+	return fw
+}
+
+// startFileWatcher polls the files in the background; later calls do nothing.
+func startFileWatcher() {
+	fileWatchOnce.Do(func() {
+		fw := newFileWatcher()
+
+		go func() {
+			ticker := time.NewTicker(fileWatchInterval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				fw.check()
+			}
+		}()
+	})
+}
+
+// check reloads the files that changed since the last check.
+func (fw *fileWatcher) check() {
+
+	if s := statFile(overridesFilePath()); s != fw.overrides {
+		fw.overrides = s
+		logger.Info("Overrides: file changed, reloading", "path", overridesFilePath())
+		overridesReload()
+	}
+
+	// -serve runs without a config file
+	if len(currentConfig().File) == 0 {
+		return
+	}
+
+	s := statFile(configFileName())
+	if s == fw.config || !s.exists {
+		return
+	}
+
+	// A running refresh reads the config file itself; try again on the next tick
+	if !refreshMu.TryLock() {
+		return
+	}
+	defer refreshMu.Unlock()
+
+	if err := reloadConfig(); err != nil {
+		logger.Error("Config: unable to reload the changed config file", "filename", configFileName(), "error", err)
+	}
+
+	// Config.Open saves the file again when it adds new default options
+	fw.config = statFile(configFileName())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileWatcherReloadsOverrides(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	dir := t.TempDir()
+	Config.Files.Cache = filepath.Join(dir, "config_cache.json")
+
+	write := func(data string, modTime time.Time) {
+		if err := os.WriteFile(overridesFilePath(), []byte(data), 0644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		if err := os.Chtimes(overridesFilePath(), modTime, modTime); err != nil {
+			t.Fatalf("os.Chtimes() error = %v", err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("Jeopardy!,p12345_b_v2\nThe Simpsons,p67890_b_h6\n", start)
+	overridesReload()
+
+	fw := newFileWatcher()
+	write("Jeopardy!,p12345_b_v3\nFrasier,p11111_b_v1\n", start.Add(time.Minute))
+	fw.check()
+
+	if id, ok := overrideImageForTitle("jeopardy!"); !ok || id != "p12345_b_v3" {
+		t.Errorf("overrideImageForTitle(jeopardy!) = %q, %v; want the changed override", id, ok)
+	}
+	if _, ok := overrideImageForTitle("the simpsons"); ok {
+		t.Errorf("removed override still active")
+	}
+
+	// Unchanged file: nothing to reload
+	write("Frasier,p11111_b_v1\n", start.Add(time.Minute))
+	fw.overrides = statFile(overridesFilePath())
+	fw.check()
+	if _, ok := overrideImageForTitle("jeopardy!"); !ok {
+		t.Errorf("overrides reloaded although the file stamp did not change")
+	}
+}
+
+func TestOverridesDiff(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		overridesReload()
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Config.Files.Cache = filepath.Join(t.TempDir(), "config_cache.json")
+
+	if err := os.WriteFile(overridesFilePath(), []byte("Jeopardy!,p12345_b_v2\nThe Simpsons,p67890_b_h6\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	overridesLoad()
+
+	if err := os.WriteFile(overridesFilePath(), []byte("Jeopardy!,p12345_b_v3\nFrasier,p11111_b_v1\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	diff := overridesLoad()
+
+	want := overridesDiff{
+		Added:   []string{"frasier"},
+		Removed: []string{"the simpsons"},
+		Changed: []string{"jeopardy!"},
+	}
+	if !reflect.DeepEqual(diff.Added, want.Added) || !reflect.DeepEqual(diff.Removed, want.Removed) || !reflect.DeepEqual(diff.Changed, want.Changed) {
+		t.Errorf("diff = %+v, want %+v", diff, want)
+	}
+
+	if len(diff.dropped) != 2 {
+		t.Errorf("dropped imageIDs = %v, want p12345_b_v2 and p67890_b_h6", diff.dropped)
+	}
+}
+
+func TestConfigChanges(t *testing.T) {
+	var previous config
+	previous.Options.Schedule = 7
+	previous.Account.Password = "old"
+	previous.Station = []channel{{ID: "10001", Name: "One"}}
+
+	current := previous
+	current.Options.Schedule = 3
+	current.Account.Password = "new"
+	current.Station = []channel{{ID: "10001", Name: "One"}, {ID: "10002", Name: "Two"}}
+
+	got := configChanges(previous, current)
+	want := []string{"Account.Password", "Options.Schedule Days", "Station"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configChanges() = %v, want %v", got, want)
+	}
+
+	if got := configChanges(previous, previous); len(got) != 0 {
+		t.Errorf("configChanges() of equal configs = %v, want none", got)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Drop a stale wake-up from other tests
+	select {
+	case <-configReloaded:
+	default:
+	}
+
+	dir := t.TempDir()
+	Config = config{File: filepath.Join(dir, "config")}
+	Config.Options.RefreshSchedule = "@every 1h"
+	Config.ChannelIDs = []string{"90447", "66603"}
+	Config.FilterStations = []channel{{Name: "one HD", ID: "66603", Lineup: "DEU-1000097-DEFAULT"}}
+	if err := os.WriteFile(Config.File+".yaml", []byte("Options:\n  Schedule Days: 3\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// Loading a config fills the defaults into it, not into the global Config
+	var local = config{File: Config.File}
+	if err := local.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if local.Options.RefreshSchedule != defaultRefreshSchedule || Config.Options.RefreshSchedule != "@every 1h" {
+		t.Fatalf("Refresh Schedule = %q (local), %q (global)", local.Options.RefreshSchedule, Config.Options.RefreshSchedule)
+	}
+
+	// A handler keeps the copy it took; the reload publishes a new value
+	before := currentConfig()
+	if err := reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if before.Options.RefreshSchedule != "@every 1h" || before.Options.Schedule != 0 {
+		t.Errorf("copy taken before the reload changed: %+v", before.Options)
+	}
+
+	if Config.Options.Schedule != 3 || Config.Options.RefreshSchedule != defaultRefreshSchedule {
+		t.Errorf("reloaded options = %+v", Config.Options)
+	}
+	if !reflect.DeepEqual(Config.ChannelIDs, []string{"90447", "66603"}) || len(Config.FilterStations) != 1 {
+		t.Errorf("runtime fields lost: ChannelIDs %v, FilterStations %v", Config.ChannelIDs, Config.FilterStations)
+	}
+
+	select {
+	case <-configReloaded:
+	default:
+		t.Errorf("daemon loop was not woken after the reload")
+	}
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bytes"
 	"encoding/xml"
 	"epgo/tmdb"
 	"fmt"
-	"os"
+	"net/url"
 	"path/filepath"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -34,6 +34,141 @@ func configuredStationName(stationID string) string {
 	return ""
 }
 
+func configuredStationNumber(stationID string) string {
+	if st, ok := configuredStation(stationID); ok {
+		return strings.TrimSpace(st.Number)
+	}
+	return ""
+}
+
+// configuredStation returns the Station entry of the config for stationID.
+func configuredStation(stationID string) (channel, bool) {
+	id := normalizeStationID(stationID)
+	for _, st := range Config.Station {
+		if normalizeStationID(st.ID) == id {
+			return st, true
+		}
+	}
+	return channel{}, false
+}
+
+// xmltvChannelID returns the configured XMLTV ID, else <stationID>.schedulesdirect.org.
+func xmltvChannelID(stationID string) string {
+	if st, ok := configuredStation(stationID); ok {
+		if id := strings.TrimSpace(st.XMLTVID); id != "" {
+			return id
+		}
+	}
+	return normalizeStationID(stationID) + schedulesDirectSuffix
+}
+
+// stationTimeOffset returns the configured Time Offset of a station.
+func stationTimeOffset(stationID string) time.Duration {
+	if st, ok := configuredStation(stationID); ok {
+		return time.Duration(st.TimeOffset * float64(time.Hour))
+	}
+	return 0
+}
+
+// channelNumber returns the configured station Number, else the number from the lineup map.
+func channelNumber(cache EPGoCache) string {
+	if number := configuredStationNumber(cache.StationID); number != "" {
+		return number
+	}
+	return strings.TrimSpace(cache.ChannelNumber)
+}
+
+// sortedChannels returns the cached channels in a stable order: the order of
+// the Station list in the config, or by channel number (configured Number or the
+// lineup's channel) with "Sort Channels By: number".
+// Channels without a position (or number) follow, ordered by station ID.
+func sortedChannels() []EPGoCache {
+	position := make(map[string]int, len(Config.Station))
+	for i, st := range Config.Station {
+		id := normalizeStationID(st.ID)
+		if _, ok := position[id]; !ok {
+			position[id] = i
+		}
+	}
+
+	channels := make([]EPGoCache, 0, len(Cache.Channel))
+	for _, ch := range Cache.Channel {
+		channels = append(channels, ch)
+	}
+
+	byNumber := strings.EqualFold(strings.TrimSpace(Config.Options.SortChannelsBy), "number")
+
+	sort.SliceStable(channels, func(i, j int) bool {
+		a, b := channels[i], channels[j]
+
+		if byNumber {
+			if c := compareChannelNumbers(channelNumber(a), channelNumber(b)); c != 0 {
+				return c < 0
+			}
+		}
+
+		pa, oka := position[normalizeStationID(a.StationID)]
+		pb, okb := position[normalizeStationID(b.StationID)]
+		if oka != okb {
+			return oka
+		}
+		if oka && pa != pb {
+			return pa < pb
+		}
+
+		return a.StationID < b.StationID
+	})
+
+	return channels
+}
+
+// compareChannelNumbers compares channel numbers such as "7", "5.1" or "12-2"
+// numerically part by part. Empty numbers sort last.
+func compareChannelNumbers(a, b string) int {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	split := func(r rune) bool { return r == '.' || r == '-' || r == ' ' }
+	pa, pb := strings.FieldsFunc(a, split), strings.FieldsFunc(b, split)
+
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, erra := strconv.Atoi(pa[i])
+		nb, errb := strconv.Atoi(pb[i])
+		switch {
+		case erra == nil && errb == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case erra == nil:
+			return -1
+		case errb == nil:
+			return 1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
 func orderedChannelDisplayNames(stationName, callsign string) []DisplayName {
 	names := make([]DisplayName, 0, 2)
 	sn := strings.TrimSpace(stationName)
@@ -48,23 +183,130 @@ func orderedChannelDisplayNames(stationName, callsign string) []DisplayName {
 	return names
 }
 
+func hasDisplayName(names []DisplayName, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n.Value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendDisplayNames appends the non-empty extra names not already in names.
+func appendDisplayNames(names []DisplayName, extra []string) []DisplayName {
+	for _, name := range extra {
+		name = strings.TrimSpace(name)
+		if name != "" && !hasDisplayName(names, name) {
+			names = append(names, DisplayName{Value: name})
+		}
+	}
+	return names
+}
+
 func buildXMLChannel(cache EPGoCache) channel {
 	var xmlCha channel // defined in struct_config.go
-	xmlCha.ID = fmt.Sprintf("%s.schedulesdirect.org", normalizeStationID(cache.StationID))
+	xmlCha.ID = xmltvChannelID(cache.StationID)
 	xmlCha.Icon = cache.getLogo()
 	stationName := configuredStationName(cache.StationID)
 	if stationName == "" {
 		stationName = cache.Name
 	}
 	xmlCha.DisplayName = append(xmlCha.DisplayName, orderedChannelDisplayNames(stationName, cache.Callsign)...)
+
+	if st, ok := configuredStation(cache.StationID); ok {
+		if logo := strings.TrimSpace(st.Logo); logo != "" {
+			xmlCha.Icon = Icon{Src: logo}
+		}
+		xmlCha.DisplayName = appendDisplayNames(xmlCha.DisplayName, st.DisplayNames)
+	}
+
+	if number := channelNumber(cache); number != "" {
+		xmlCha.DisplayName = append(xmlCha.DisplayName, DisplayName{Value: number})
+		xmlCha.LCN = number
+	}
 	return xmlCha
 }
 
-// CreateXMLTV : Create XMLTV file from cache file
+// virtualChannel is a Virtual Station from the config with its cached source station.
+type virtualChannel struct {
+	Station virtualStation
+	Source  EPGoCache
+}
+
+// virtualChannels resolves the Virtual Station entries of the config against
+// the cached stations. Entries without ID or with an unknown source are skipped.
+func virtualChannels() (channels []virtualChannel) {
+	for _, v := range Config.VirtualStation {
+		id := strings.TrimSpace(v.ID)
+		if id == "" {
+			logger.Warn("Virtual Station without ID skipped", "name", v.Name, "source", v.Source)
+			continue
+		}
+
+		source, ok := Cache.Channel[normalizeStationID(v.Source)]
+		if !ok {
+			logger.Warn("Virtual Station source not found in the cache. Add the source to the Station list", "id", id, "source", v.Source)
+			continue
+		}
+
+		channels = append(channels, virtualChannel{Station: v, Source: source})
+	}
+	return
+}
+
+func buildVirtualChannel(v virtualChannel) channel {
+	var xmlCha channel // defined in struct_config.go
+	xmlCha.ID = strings.TrimSpace(v.Station.ID)
+	xmlCha.Icon = v.Source.getLogo()
+	if logo := strings.TrimSpace(v.Station.Logo); logo != "" {
+		xmlCha.Icon = Icon{Src: logo}
+	}
+
+	name := strings.TrimSpace(v.Station.Name)
+	if name == "" {
+		name = xmlCha.ID
+	}
+	xmlCha.DisplayName = appendDisplayNames(nil, append([]string{name}, v.Station.DisplayNames...))
+
+	if number := strings.TrimSpace(v.Station.Number); number != "" {
+		xmlCha.DisplayName = append(xmlCha.DisplayName, DisplayName{Value: number})
+		xmlCha.LCN = number
+	}
+	return xmlCha
+}
+
+// CreateXMLTV : Create XMLTV file from cache file (and one per configured Output)
 func CreateXMLTV(filename string) (err error) {
-	defer func() { runtime.GC() }()
 
-	Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
+	if _, err = loadConfig(strings.TrimSuffix(filename, filepath.Ext(filename))); err != nil {
+		return
+	}
+	if err = Cache.Open(); err != nil {
+		return
+	}
+	Cache.Init()
+	if err = Cache.Open(); err != nil {
+		logger.Error("unable to open the cache", "error", err)
+		return
+	}
+
+	// Main XMLTV file and the additional Outputs, all from the same cache
+	for _, profile := range Config.xmltvProfiles() {
+		if err = writeXMLTV(profile); err != nil {
+			logger.Error("unable to create the XMLTV file", "output", profile.Name, "filename", profile.XMLTV, "error", err)
+			return
+		}
+	}
+
+	return
+}
+
+// writeXMLTV writes the XMLTV file of one output profile.
+//
+// The document is streamed to a temporary file next to the XMLTV file (plus the
+// optional .gz/.xz copies), one channel's programmes at a time, and renamed into
+// place once complete.
+func writeXMLTV(profile xmltvProfile) (err error) {
 
 	var generator xml.Attr
 	generator.Name = xml.Name{Local: "EPGo"}
@@ -78,10 +320,19 @@ func CreateXMLTV(filename string) (err error) {
 	info.Name = xml.Name{Local: "source-info-url"}
 	info.Value = "http://schedulesdirect.org"
 
-	buf := &bytes.Buffer{}
-	buf.WriteString(xml.Header)
+	logger.Info("Create XMLTV File", "filename", profile.XMLTV, "output", profile.Name)
+
+	w, err := newXMLTVOutput(profile.XMLTV, profile.Gzip, profile.Xz)
+	if err != nil {
+		return
+	}
+	defer w.Abort()
 
-	enc := xml.NewEncoder(buf)
+	if _, err = w.WriteString(xml.Header); err != nil {
+		return
+	}
+
+	enc := xml.NewEncoder(w)
 	enc.Indent("", "  ")
 
 	he := func(err error) {
@@ -90,47 +341,94 @@ func CreateXMLTV(filename string) (err error) {
 		}
 	}
 
-	if err = Config.Open(); err != nil {
-		return
-	}
-	if err = Cache.Open(); err != nil {
-		return
-	}
-	Cache.Init()
-	if err = Cache.Open(); err != nil {
-		logger.Error("unable to open the cache", "error", err)
-		return
-	}
-
-	logger.Info("Create XMLTV File", "filename", Config.Files.XMLTV)
-
 	he(enc.EncodeToken(xml.StartElement{
 		Name: xml.Name{Local: "tv"},
 		Attr: []xml.Attr{generator, source, info},
 	}))
 
-	// Channels
-	for _, cache := range Cache.Channel {
+	var channels []EPGoCache
+	for _, cache := range sortedChannels() {
+		if profile.includes(cache.StationID) {
+			channels = append(channels, cache)
+		}
+	}
+
+	var virtuals []virtualChannel
+	for _, v := range virtualChannels() {
+		if profile.includes(v.Station.ID) {
+			virtuals = append(virtuals, v)
+		}
+	}
+
+	// Channels (virtual stations follow the SD stations)
+	for _, cache := range channels {
 		xmlCha := buildXMLChannel(cache)
 		he(enc.Encode(xmlCha))
 	}
+	for _, v := range virtuals {
+		he(enc.Encode(buildVirtualChannel(v)))
+	}
 
-	// Programmes
-	for _, cache := range Cache.Channel {
-		progs := getProgram(cache)
+	// Programmes (flushed per channel to keep memory bounded)
+	for _, cache := range channels {
+		progs := getProgram(cache, profile)
 		he(enc.Encode(progs))
+		if err = enc.Flush(); err != nil {
+			return
+		}
+	}
+	for _, v := range virtuals {
+		shift := time.Duration(v.Station.TimeOffset * float64(time.Hour))
+		progs := getShiftedProgram(v.Source, strings.TrimSpace(v.Station.ID), shift, profile)
+		he(enc.Encode(progs))
+		if err = enc.Flush(); err != nil {
+			return
+		}
 	}
 
 	he(enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "tv"}}))
-	he(enc.Flush())
 
-	// Write file
-	if err = os.WriteFile(Config.Files.XMLTV, buf.Bytes(), 0644); err != nil {
+	if err = enc.Flush(); err != nil {
 		return
 	}
+
+	// Replace the XMLTV file (and its compressed copies)
+	return w.Commit()
+}
+
+// loadOutputTimezone resolves the Output Timezone once; empty means UTC.
+// Config.Open calls it, so an unknown name is reported when the config is loaded.
+func (c *config) loadOutputTimezone() (err error) {
+	c.outputLocation = time.UTC
+
+	name := strings.TrimSpace(c.Options.OutputTimezone)
+	if name == "" {
+		return
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid Output Timezone %q: %w", name, err)
+	}
+	c.outputLocation = loc
 	return
 }
 
+// location returns the Output Timezone resolved by loadOutputTimezone, UTC
+// for a config that was not loaded from a file.
+func (c *config) location() *time.Location {
+	if c.outputLocation == nil {
+		return time.UTC
+	}
+	return c.outputLocation
+}
+
+// xmltvTime formats t as an XMLTV timestamp in loc, e.g. "20240331013000 +0100".
+// The offset is the one in effect at t, so DST changes are handled per programme.
+func xmltvTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("20060102150405 -0700")
+}
+
 // Channel infos
 func (channel *EPGoCache) getLogo() (icon Icon) {
 	icon.Src = channel.Logo.URL
@@ -139,31 +437,49 @@ func (channel *EPGoCache) getLogo() (icon Icon) {
 	return
 }
 
-func getProgram(channel EPGoCache) (p []Programme) {
-	schedule, ok := Cache.Schedule[channel.StationID]
+func getProgram(channel EPGoCache, profile xmltvProfile) (p []Programme) {
+	return getShiftedProgram(channel, xmltvChannelID(channel.StationID), stationTimeOffset(channel.StationID), profile)
+}
+
+// getShiftedProgram returns the programmes of the station's cached schedule for
+// the XMLTV channel channelID, moved by shift, with the options of profile.
+func getShiftedProgram(channel EPGoCache, channelID string, shift time.Duration, profile xmltvProfile) (p []Programme) {
+	// Copy under the lock, the proxy adds metadata to the cache meanwhile
+	Cache.RLock()
+	cached, ok := Cache.Schedule[channel.StationID]
+	schedule := make([]EPGoCache, len(cached))
+	copy(schedule, cached)
+	Cache.RUnlock()
 	if !ok {
 		return
 	}
 
+	// Programmes in start time order
+	sort.SliceStable(schedule, func(i, j int) bool {
+		return schedule[i].AirDateTime.Before(schedule[j].AirDateTime)
+	})
+
+	loc := Config.location()
+	from, to := scheduleWindow(time.Now())
+
 	for _, s := range schedule {
 		var pro Programme
 
+		// Skip programmes outside the schedule window
+		start := s.AirDateTime.Add(shift)
+		stop := start.Add(time.Second * time.Duration(s.Duration))
+		if !stop.After(from) || !start.Before(to) {
+			continue
+		}
+
 		countryCode := Config.GetLineupCountry(channel.StationID)
 
 		// Channel ID
-		pro.Channel = fmt.Sprintf("%s.schedulesdirect.org", channel.StationID)
+		pro.Channel = channelID
 
-		// Start and Stop time
-		timeLayout := "2006-01-02 15:04:05 +0000 UTC"
-		t, err := time.Parse(timeLayout, s.AirDateTime.Format(timeLayout))
-		if err != nil {
-			logger.Error("unable to parse the time", "error", err)
-			return
-		}
-		dateArray := strings.Fields(t.String())
-		offset := " " + dateArray[2]
-		pro.Start = t.Format("20060102150405") + offset
-		pro.Stop = t.Add(time.Second*time.Duration(s.Duration)).Format("20060102150405") + offset
+		// Start and Stop time (shifted for timeshift channels)
+		pro.Start = xmltvTime(start, loc)
+		pro.Stop = xmltvTime(stop, loc)
 
 		// Title
 		lang := "en"
@@ -174,10 +490,10 @@ func getProgram(channel EPGoCache) (p []Programme) {
 		baseTitle := pro.Title[0].Value
 
 		// New and Live guide mini-icons
-		if s.LiveTapeDelay == "Live" && Config.Options.LiveIcons {
+		if s.LiveTapeDelay == "Live" && profile.LiveIcons {
 			pro.Title[0].Value = pro.Title[0].Value + " ᴸᶦᵛᵉ"
 		}
-		if s.New && s.LiveTapeDelay != "Live" && Config.Options.LiveIcons {
+		if s.New && s.LiveTapeDelay != "Live" && profile.LiveIcons {
 			pro.Title[0].Value = pro.Title[0].Value + " ᴺᵉʷ"
 		}
 
@@ -188,7 +504,9 @@ func getProgram(channel EPGoCache) (p []Programme) {
 		pro.Desc = Cache.GetDescs(s.ProgramID, pro.SubTitle.Value)
 
 		// Credits
-		pro.Credits = Cache.GetCredits(s.ProgramID)
+		if profile.Credits {
+			pro.Credits = Cache.GetCredits(s.ProgramID)
+		}
 
 		// Category
 		pro.Categorys = Cache.GetCategory(s.ProgramID)
@@ -199,73 +517,87 @@ func getProgram(channel EPGoCache) (p []Programme) {
 		// EpisodeNum
 		pro.EpisodeNums = Cache.GetEpisodeNum(s.ProgramID)
 
-		// -------------------------
-		// Icon selection
-		// SD (pinned) → TMDb → blank
-		// -------------------------
-		imageURL := ""
-		pinnedImageID := ""
-
-		if overrideID, ok := overrideImageForProgramOrTitle(s.ProgramID, baseTitle); ok {
-			pinnedImageID = overrideID
-		}
-		proxyURL := func() string {
-			base := strings.TrimRight(Config.Options.Images.ProxyBaseURL, "/")
-			if base == "" {
-				base = "http://" + Config.Server.Address + ":" + Config.Server.Port
+		if profile.Icons {
+			// -------------------------
+			// Icon selection
+			// SD (pinned) → TMDb → blank
+			// -------------------------
+			imageURL := ""
+			pinnedImageID := ""
+			query := ""
+
+			if override, ok := overrideImageForProgramOrTitle(s.ProgramID, channel.StationID, baseTitle); ok {
+				pinnedImageID = override.ImageID
+				// The proxy needs the station to find a station override again
+				if len(override.Station) != 0 {
+					query = "?station=" + url.QueryEscape(override.Station)
+				}
+			}
+			proxyURL := func() string {
+				base := strings.TrimRight(Config.Options.Images.ProxyBaseURL, "/")
+				if base == "" {
+					base = "http://" + Config.Server.Address + ":" + Config.Server.Port
+				}
+				// Outputs with their own Poster Aspect pin the chosen SD image
+				if isSDImageID(pinnedImageID) && profile.ownPosterAspect() {
+					return base + "/proxy/sd/" + s.ProgramID + "/" + pinnedImageID + query
+				}
+				return base + "/proxy/sd/" + s.ProgramID + query
 			}
-			return base + "/proxy/sd/" + s.ProgramID
-		}
 
-		if pinnedImageID != "" && Config.Options.Images.ProxyMode && Config.Server.Enable {
-			imageURL = proxyURL()
-		}
+			if pinnedImageID != "" && Config.Options.Images.ProxyMode && Config.Server.Enable {
+				imageURL = proxyURL()
+			}
 
-		if Config.Options.Images.ProxyMode && Config.Server.Enable {
-			if imageURL == "" {
-				// Try SD pin
-				if chosenID, _, ok := Cache.GetChosenSDImage(s.ProgramID); ok {
-					pinnedImageID = chosenID
-					imageURL = proxyURL()
+			if Config.Options.Images.ProxyMode && Config.Server.Enable {
+				if imageURL == "" {
+					// Try SD pin
+					if chosenID, _, ok := Cache.GetChosenSDImageForAspect(s.ProgramID, profile.PosterAspect); ok {
+						pinnedImageID = chosenID
+						imageURL = proxyURL()
+					}
+					// else: leave empty to allow TMDb fallback
 				}
-				// else: leave empty to allow TMDb fallback
-			}
-		} else {
-			// Non-proxy mode: direct SD or pre-downloaded
-			icons := Cache.GetIcon(s.ProgramID)
-			if len(icons) != 0 {
-				if Config.Options.Images.Download {
-					// Eager download mode
-					imageURL = "http://" + Config.Server.Address + ":" + Config.Server.Port + "/" + s.ProgramID + ".jpg"
-				} else {
-					// Raw SD URL (expiring token)
-					imageURL = icons[0].Src
+			} else {
+				// Non-proxy mode: direct SD or pre-downloaded
+				icons := Cache.GetIconForAspect(s.ProgramID, profile.PosterAspect)
+				if len(icons) != 0 {
+					if Config.Options.Images.Download && !profile.ownPosterAspect() {
+						// Eager download mode
+						imageURL = "http://" + Config.Server.Address + ":" + Config.Server.Port + "/" + s.ProgramID + ".jpg"
+					} else {
+						// Raw SD URL (expiring token)
+						imageURL = icons[0].Src
+					}
 				}
 			}
-		}
 
-                // TMDb fallback (only if nothing from SD)
-                if imageURL == "" && Config.Options.Images.Tmdb.Enable {
-			seas := ""
-			if len(pro.EpisodeNums) > 0 && len(pro.EpisodeNums[0].Value) >= 2 {
-				seas = pro.EpisodeNums[0].Value[0:2]
-			}
-			imageURL, err = tmdb.SearchItem(
-				logger,
-				pro.Title[0].Value,
-				seas,
-				Config.Options.Images.Tmdb.ApiKey,
-				Config.Files.TmdbCacheFile,
-			)
-			if err != nil {
-				logger.Error("tmdb lookup failed", "error", err)
+			// TMDb fallback (only if nothing from SD)
+			if imageURL == "" && Config.Options.Images.Tmdb.Enable {
+				seas := ""
+				if len(pro.EpisodeNums) > 0 && len(pro.EpisodeNums[0].Value) >= 2 {
+					seas = pro.EpisodeNums[0].Value[0:2]
+				}
+				var err error
+				imageURL, err = tmdb.SearchItem(
+					logger,
+					pro.Title[0].Value,
+					seas,
+					Config.Options.Images.Tmdb.ApiKey,
+					Config.Files.TmdbCacheFile,
+				)
+				if err != nil {
+					logger.Error("tmdb lookup failed", "error", err)
+				}
 			}
-		}
 
-		pro.Icon = []Icon{{Src: imageURL}}
+			pro.Icon = []Icon{{Src: imageURL}}
+		}
 
 		// Rating
-		pro.Rating = Cache.GetRating(s.ProgramID, countryCode)
+		if profile.Rating {
+			pro.Rating = Cache.GetRating(s.ProgramID, countryCode)
+		}
 
 		// Video
 		for _, v := range s.VideoProperties {
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+
+	"github.com/ulikunitz/xz"
+
+	"epgo/internal/atomicfile"
+)
+
+// xmltvOutput fans the XMLTV stream out to the plain file and the optional
+// compressed copies (<file>.gz, <file>.xz). All files are written atomically
+// and only replaced together on Commit.
+type xmltvOutput struct {
+	*bufio.Writer
+
+	files       []*atomicfile.File
+	compressors []io.WriteCloser
+}
+
+func newXMLTVOutput(path string, gz, xzip bool) (o *xmltvOutput, err error) {
+
+	o = &xmltvOutput{}
+	defer func() {
+		if err != nil {
+			o.Abort()
+		}
+	}()
+
+	var writers []io.Writer
+
+	plain, err := atomicfile.Create(path, 0644)
+	if err != nil {
+		return
+	}
+	o.files = append(o.files, plain)
+	writers = append(writers, plain)
+
+	if gz {
+		var f *atomicfile.File
+		var zw *gzip.Writer
+
+		f, err = atomicfile.Create(path+".gz", 0644)
+		if err != nil {
+			return
+		}
+		o.files = append(o.files, f)
+
+		zw, err = gzip.NewWriterLevel(f, gzip.BestCompression)
+		if err != nil {
+			return
+		}
+		o.compressors = append(o.compressors, zw)
+		writers = append(writers, zw)
+	}
+
+	if xzip {
+		var f *atomicfile.File
+		var zw *xz.Writer
+
+		f, err = atomicfile.Create(path+".xz", 0644)
+		if err != nil {
+			return
+		}
+		o.files = append(o.files, f)
+
+		zw, err = xz.NewWriter(f)
+		if err != nil {
+			return
+		}
+		o.compressors = append(o.compressors, zw)
+		writers = append(writers, zw)
+	}
+
+	o.Writer = bufio.NewWriter(io.MultiWriter(writers...))
+	return
+}
+
+// Commit flushes all streams and replaces the plain file first, so a
+// compressed copy is never newer than a plain file it does not match.
+func (o *xmltvOutput) Commit() (err error) {
+
+	if err = o.Writer.Flush(); err != nil {
+		return
+	}
+
+	for _, c := range o.compressors {
+		if err = c.Close(); err != nil {
+			return
+		}
+	}
+
+	for _, f := range o.files {
+		if err = f.Commit(); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Abort discards every file that has not been committed.
+func (o *xmltvOutput) Abort() {
+	for _, f := range o.files {
+		f.Abort()
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// xmltvProfile holds the effective settings of one XMLTV file.
+type xmltvProfile struct {
+	Name         string
+	XMLTV        string
+	Gzip         bool
+	Xz           bool
+	Stations     map[string]bool // nil = all stations
+	PosterAspect string
+	Credits      bool
+	Rating       bool
+	LiveIcons    bool
+	Icons        bool
+}
+
+// defaultXMLTVProfile returns the main XMLTV file as configured in Files and Options.
+func (c *config) defaultXMLTVProfile() xmltvProfile {
+	return xmltvProfile{
+		Name:         "default",
+		XMLTV:        c.Files.XMLTV,
+		Gzip:         c.Files.XMLTVGzip,
+		Xz:           c.Files.XMLTVXz,
+		PosterAspect: c.Options.Images.PosterAspect,
+		Credits:      c.Options.Credits,
+		Rating:       c.Options.Rating.Guidelines,
+		LiveIcons:    c.Options.LiveIcons,
+		Icons:        true,
+	}
+}
+
+// xmltvProfiles returns the main XMLTV file followed by the configured Outputs.
+// Outputs without XMLTV file name are written to <config>_<name>.xml.
+func (c *config) xmltvProfiles() (profiles []xmltvProfile) {
+	profiles = append(profiles, c.defaultXMLTVProfile())
+
+	for i, o := range c.Outputs {
+		p := c.defaultXMLTVProfile()
+
+		p.Name = strings.TrimSpace(o.Name)
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("output%d", i+1)
+		}
+
+		p.XMLTV = strings.TrimSpace(o.XMLTV)
+		if p.XMLTV == "" {
+			p.XMLTV = fmt.Sprintf("%s_%s.xml", c.File, p.Name)
+		}
+		p.Gzip, p.Xz = o.XMLTVGzip, o.XMLTVXz
+
+		if len(o.Stations) != 0 {
+			p.Stations = make(map[string]bool, len(o.Stations))
+			for _, id := range o.Stations {
+				p.Stations[normalizeStationID(id)] = true
+			}
+		}
+
+		if o.PosterAspect != nil {
+			p.PosterAspect = *o.PosterAspect
+		}
+		if o.Credits != nil {
+			p.Credits = *o.Credits
+		}
+		if o.Rating != nil {
+			p.Rating = *o.Rating
+		}
+		if o.LiveIcons != nil {
+			p.LiveIcons = *o.LiveIcons
+		}
+		if o.Icons != nil {
+			p.Icons = *o.Icons
+		}
+
+		if p.XMLTV == c.Files.XMLTV {
+			logger.Warn("Output skipped; XMLTV file is the main XMLTV file", "output", p.Name, "file", p.XMLTV)
+			continue
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	return
+}
+
+// includes reports whether the station (or virtual station) ID is part of the profile.
+func (p xmltvProfile) includes(stationID string) bool {
+	return p.Stations == nil || p.Stations[normalizeStationID(stationID)]
+}
+
+// ownPosterAspect reports whether the profile selects posters with another
+// Poster Aspect than the proxy, which resolves /proxy/sd/{programID} with the
+// aspect from Options.
+func (p xmltvProfile) ownPosterAspect() bool {
+	return !strings.EqualFold(strings.TrimSpace(p.PosterAspect), strings.TrimSpace(Config.Options.Images.PosterAspect))
+}
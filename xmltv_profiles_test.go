@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestXMLTVProfiles(t *testing.T) {
+	original := Config
+	originalLogger := logger
+	defer func() {
+		Config = original
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	aspect := "16x9"
+	off, on := false, true
+
+	Config.File = "/app/config"
+	Config.Files.XMLTV = "/app/config.xml"
+	Config.Options.Images.PosterAspect = "2x3"
+	Config.Options.Credits = false
+	Config.Options.Rating.Guidelines = false
+	Config.Options.LiveIcons = true
+	Config.Outputs = []outputProfile{
+		{Name: "plex", XMLTV: "/app/plex.xml", Stations: []string{"90447", "66603.schedulesdirect.org"}, LiveIcons: &off},
+		{Name: "jellyfin", PosterAspect: &aspect, Credits: &on, Rating: &on},
+		{Name: "clash", XMLTV: "/app/config.xml"},
+	}
+
+	profiles := Config.xmltvProfiles()
+	if len(profiles) != 3 {
+		t.Fatalf("xmltvProfiles() returned %d profiles, want 3", len(profiles))
+	}
+
+	main, plex, jellyfin := profiles[0], profiles[1], profiles[2]
+
+	if main.XMLTV != "/app/config.xml" || !main.includes("12345") || main.ownPosterAspect() {
+		t.Fatalf("main profile = %+v, want the Files/Options settings", main)
+	}
+
+	if plex.XMLTV != "/app/plex.xml" || plex.LiveIcons || plex.PosterAspect != "2x3" {
+		t.Fatalf("plex profile = %+v", plex)
+	}
+	if !plex.includes("90447") || !plex.includes("66603") || plex.includes("12345") {
+		t.Fatalf("plex stations = %v, want 90447 and 66603 only", plex.Stations)
+	}
+
+	if jellyfin.XMLTV != "/app/config_jellyfin.xml" {
+		t.Fatalf("jellyfin XMLTV = %q, want %q", jellyfin.XMLTV, "/app/config_jellyfin.xml")
+	}
+	if !jellyfin.Credits || !jellyfin.Rating || !jellyfin.LiveIcons || !jellyfin.Icons {
+		t.Fatalf("jellyfin profile = %+v, want credits, rating and inherited icons", jellyfin)
+	}
+	if !jellyfin.ownPosterAspect() {
+		t.Fatalf("jellyfin ownPosterAspect() = false, want true for %q", jellyfin.PosterAspect)
+	}
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"encoding/xml"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestOrderedChannelDisplayNames(t *testing.T) {
@@ -149,3 +154,283 @@ func TestBuildXMLChannelDisplayNameOrderMarshalledXML(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareChannelNumbers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "2", b: "10", want: -1},
+		{a: "5.1", b: "5.2", want: -1},
+		{a: "5", b: "5.1", want: -1},
+		{a: "12-2", b: "12.3", want: -1},
+		{a: "7", b: "", want: -1},
+		{a: "", b: "7", want: 1},
+		{a: "101", b: "101", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareChannelNumbers(tt.a, tt.b); got != tt.want {
+			t.Fatalf("compareChannelNumbers(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortedChannels(t *testing.T) {
+	original := Config
+	originalChannels := Cache.Channel
+	defer func() {
+		Config = original
+		Cache.Channel = originalChannels
+	}()
+
+	Config.Station = []channel{
+		{Name: "Eurosport 1", ID: "66603", Number: "12"},
+		{Name: "Das Erste HD", ID: "90447", Number: "1"},
+		{Name: "one HD", ID: "90457"},
+	}
+	Cache.Channel = map[string]EPGoCache{
+		"90457": {StationID: "90457"},
+		"11111": {StationID: "11111"},
+		"90447": {StationID: "90447"},
+		"66603": {StationID: "66603"},
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{sortBy: "config", want: []string{"66603", "90447", "90457", "11111"}},
+		{sortBy: "number", want: []string{"90447", "66603", "90457", "11111"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			Config.Options.SortChannelsBy = tt.sortBy
+			for run := 0; run < 5; run++ {
+				got := sortedChannels()
+				for i := range tt.want {
+					if got[i].StationID != tt.want[i] {
+						t.Fatalf("sortedChannels()[%d] = %s, want %s", i, got[i].StationID, tt.want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLineupChannelNumbers(t *testing.T) {
+	maps := []SDChannelMap{
+		{StationID: "10001", Channel: "5"},
+		{StationID: "10001", Channel: "105"},
+		{StationID: "10002", LogicalChannelNumber: "7"},
+		{StationID: "10003", AtscMajor: 12, AtscMinor: 2},
+		{StationID: "10004"},
+	}
+
+	got := lineupChannelNumbers(maps)
+	want := map[string]string{"10001": "5", "10002": "7", "10003": "12.2"}
+
+	if len(got) != len(want) {
+		t.Fatalf("lineupChannelNumbers() = %v, want %v", got, want)
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Fatalf("lineupChannelNumbers()[%q] = %q, want %q", id, got[id], n)
+		}
+	}
+}
+
+func TestBuildXMLChannelStationCustomization(t *testing.T) {
+	original := Config
+	defer func() { Config = original }()
+
+	Config.Station = []channel{{
+		Name:         "Das Erste HD",
+		ID:           "90447",
+		XMLTVID:      "DasErste.de",
+		Logo:         "https://example.com/daserste.png",
+		DisplayNames: []string{"ARD", "das erste hd", " "},
+		TimeOffset:   1,
+	}}
+
+	cache := EPGoCache{StationID: "90447", Callsign: "ARDGRHD"}
+	cache.Logo.URL = "https://sd/logo.png"
+
+	ch := buildXMLChannel(cache)
+
+	if ch.ID != "DasErste.de" {
+		t.Fatalf("channel ID = %q, want %q", ch.ID, "DasErste.de")
+	}
+	if ch.Icon.Src != "https://example.com/daserste.png" {
+		t.Fatalf("icon = %q, want logo override", ch.Icon.Src)
+	}
+
+	want := []string{"Das Erste HD", "ARDGRHD", "ARD"}
+	if len(ch.DisplayName) != len(want) {
+		t.Fatalf("display-names = %v, want %v", ch.DisplayName, want)
+	}
+	for i := range want {
+		if ch.DisplayName[i].Value != want[i] {
+			t.Fatalf("display-name[%d] = %q, want %q", i, ch.DisplayName[i].Value, want[i])
+		}
+	}
+
+	if got := stationTimeOffset("90447"); got != time.Hour {
+		t.Fatalf("stationTimeOffset() = %v, want %v", got, time.Hour)
+	}
+	if got := xmltvChannelID("66603"); got != "66603.schedulesdirect.org" {
+		t.Fatalf("xmltvChannelID() = %q, want default ID", got)
+	}
+}
+
+func TestVirtualChannels(t *testing.T) {
+	original := Config
+	originalChannels := Cache.Channel
+	originalLogger := logger
+	defer func() {
+		Config = original
+		Cache.Channel = originalChannels
+		logger = originalLogger
+	}()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	Cache.Channel = map[string]EPGoCache{
+		"90447": {StationID: "90447", Callsign: "ARDGRHD"},
+	}
+	Config.VirtualStation = []virtualStation{
+		{Name: "Das Erste HD +1", ID: "daserste-plus1", Source: "90447", TimeOffset: 1, Number: "101"},
+		{Name: "Unknown +1", ID: "unknown-plus1", Source: "12345", TimeOffset: 1},
+		{Name: "Missing ID", Source: "90447", TimeOffset: 2},
+	}
+
+	got := virtualChannels()
+	if len(got) != 1 {
+		t.Fatalf("virtualChannels() returned %d channels, want 1", len(got))
+	}
+	if got[0].Source.StationID != "90447" {
+		t.Fatalf("virtual source = %q, want %q", got[0].Source.StationID, "90447")
+	}
+
+	ch := buildVirtualChannel(got[0])
+	if ch.ID != "daserste-plus1" {
+		t.Fatalf("channel ID = %q, want %q", ch.ID, "daserste-plus1")
+	}
+	if len(ch.DisplayName) != 2 || ch.DisplayName[0].Value != "Das Erste HD +1" || ch.DisplayName[1].Value != "101" {
+		t.Fatalf("display-names = %v, want [Das Erste HD +1 101]", ch.DisplayName)
+	}
+	if ch.LCN != "101" {
+		t.Fatalf("lcn = %q, want %q", ch.LCN, "101")
+	}
+
+	// The DTD requires display-name+, icon*, url*, lcn*
+	ch.Icon.Src = "https://example.com/daserste.png"
+	out, err := xml.Marshal(ch)
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	name, icon, lcn := strings.Index(string(out), "<display-name"), strings.Index(string(out), "<icon"), strings.Index(string(out), "<lcn>")
+	if name < 0 || icon < name || lcn < icon {
+		t.Fatalf("channel elements out of order: %s", out)
+	}
+}
+
+func TestXMLTVTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{
+			name: "utc",
+			t:    time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC),
+			loc:  time.UTC,
+			want: "20240331003000 +0000",
+		},
+		{
+			name: "berlin before DST",
+			t:    time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC),
+			loc:  berlin,
+			want: "20240331013000 +0100",
+		},
+		{
+			name: "berlin after DST",
+			t:    time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+			loc:  berlin,
+			want: "20240331033000 +0200",
+		},
+		{
+			name: "berlin end of DST",
+			t:    time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC),
+			loc:  berlin,
+			want: "20241027023000 +0100",
+		},
+		{
+			name: "new york negative offset",
+			t:    time.Date(2024, 7, 1, 2, 0, 0, 0, time.UTC),
+			loc:  newYork,
+			want: "20240630220000 -0400",
+		},
+		{
+			name: "input in another zone",
+			t:    time.Date(2024, 1, 15, 20, 15, 0, 0, berlin),
+			loc:  time.UTC,
+			want: "20240115191500 +0000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xmltvTime(tt.t, tt.loc); got != tt.want {
+				t.Fatalf("xmltvTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOutputTimezone(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{timezone: "", want: "UTC"},
+		{timezone: "UTC", want: "UTC"},
+		{timezone: " Europe/Berlin ", want: "Europe/Berlin"},
+		{timezone: "Not/AZone", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			var c config
+			c.Options.OutputTimezone = tt.timezone
+			err := c.loadOutputTimezone()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadOutputTimezone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.location().String() != tt.want {
+				t.Fatalf("location() = %q, want %q", c.location(), tt.want)
+			}
+		})
+	}
+
+	// Config.Open reports an unknown name
+	file := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(file+".yaml", []byte("Options:\n  Output Timezone: Not/AZone\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	c := config{File: file}
+	if err := c.Open(); err == nil {
+		t.Errorf("Open() with an unknown Output Timezone error = nil, want error")
+	}
+}